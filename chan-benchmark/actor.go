@@ -0,0 +1,107 @@
+package main
+
+import "sync"
+import "time"
+
+var actorCallers = []int{1, 2, 8}
+
+// command asks the owner of an account to add to its balance, and to
+// reply with the new balance.
+type command struct {
+	amount int
+	reply  chan int
+}
+
+// lockedAccount is the same state, protected by a mutex and updated by
+// direct calls.
+type lockedAccount struct {
+	mu      sync.Mutex
+	balance int
+}
+
+func (a *lockedAccount) add(amount int) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.balance += amount
+	return a.balance
+}
+
+func actorScenarios() []scenario {
+	var list []scenario
+	for _, c := range actorCallers {
+		list = append(list, scenario{"actor mailbox, " + plural(c, "caller"), actorMailbox(c), tags("macro", "scaling")})
+	}
+	for _, c := range actorCallers {
+		list = append(list, scenario{"mutex calls, " + plural(c, "caller"), mutexCalls(c), tags("macro", "scaling", "baseline")})
+	}
+	return list
+}
+
+// actorMailbox returns a scenario where callers share one owner
+// goroutine, which alone touches the state and answers every command.
+func actorMailbox(callers int) func(iterations int) result {
+	return func(iterations int) result {
+		mailbox := make(chan command)
+
+		then := time.Now()
+
+		go func() {
+			balance := 0
+			for c := range mailbox {
+				balance += c.amount
+				c.reply <- balance
+			}
+		}()
+
+		var wg sync.WaitGroup
+		wg.Add(callers)
+		for c := 0; c < callers; c++ {
+			go func(n int) {
+				reply := make(chan int)
+				for i := 0; i < n; i++ {
+					mailbox <- command{1, reply}
+					_ = <-reply
+				}
+				wg.Done()
+			}(iterations / callers)
+		}
+		wg.Wait()
+		close(mailbox)
+
+		return result{
+			messages:    callers * (iterations / callers),
+			elapsed:     time.Since(then),
+			sweep:       "actor mailbox",
+			parallelism: callers,
+		}
+	}
+}
+
+// mutexCalls returns a scenario where callers update the state
+// themselves, taking turns through its mutex.
+func mutexCalls(callers int) func(iterations int) result {
+	return func(iterations int) result {
+		var account lockedAccount
+
+		then := time.Now()
+
+		var wg sync.WaitGroup
+		wg.Add(callers)
+		for c := 0; c < callers; c++ {
+			go func(n int) {
+				for i := 0; i < n; i++ {
+					_ = account.add(1)
+				}
+				wg.Done()
+			}(iterations / callers)
+		}
+		wg.Wait()
+
+		return result{
+			messages:    account.balance,
+			elapsed:     time.Since(then),
+			sweep:       "mutex calls",
+			parallelism: callers,
+		}
+	}
+}
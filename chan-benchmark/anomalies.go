@@ -0,0 +1,72 @@
+package main
+
+import "fmt"
+import "runtime"
+import "time"
+
+// Thresholds above which a result is annotated as disturbed: involuntary
+// context switches per second of the run, the share of the machine used
+// by other processes, and the share of the process CPU time spent
+// collecting garbage, both in percent.
+const anomalyPreemptions = 1000
+const anomalyNeighbours = 25
+const anomalyGCShare = 10
+
+// throttling is the CPU quota throttling of the process's cgroup.
+type throttling struct {
+	periods uint64
+	time    time.Duration
+}
+
+// disturbances is a snapshot of what, besides the scenario itself, can
+// make its timing an outlier.
+type disturbances struct {
+	gcCycles  uint64
+	gcCPU     float64
+	throttled throttling
+}
+
+func readDisturbances() disturbances {
+	cycles, cpu := readGC()
+	return disturbances{cycles, cpu, readThrottling()}
+}
+
+// annotate adds a note to r for every disturbance found between the two
+// snapshots, given the resource usage of the process and the wall time
+// over the same run, and its metrics, so that outliers explain
+// themselves.
+func (d disturbances) annotate(later disturbances, before, after resourceUsage, wall time.Duration, r *result) {
+	cpu := after.cpu - before.cpu
+	if cycles := later.gcCycles - d.gcCycles; cycles > 0 && cpu > 0 {
+		share := 100 * (later.gcCPU - d.gcCPU) * 1e9 / float64(cpu)
+		if share >= anomalyGCShare {
+			r.notes = append(r.notes, fmt.Sprintf("anomaly: %s took about %s%% of the CPU time", plural(int(cycles), "GC cycle"), formatValue(share)))
+		}
+	}
+	if periods := later.throttled.periods - d.throttled.periods; periods > 0 {
+		r.notes = append(r.notes, fmt.Sprintf("anomaly: the CPU quota throttled the process %d times, for %v", periods, later.throttled.time-d.throttled.time))
+	}
+	if wall > 0 {
+		rate := float64(after.involuntary-before.involuntary) / wall.Seconds()
+		if rate >= anomalyPreemptions {
+			r.notes = append(r.notes, fmt.Sprintf("anomaly: the OS preempted the process %s times per second", formatValue(rate)))
+		}
+	}
+	machine, okMachine := metricValue(*r, "average core utilization")
+	process, okProcess := metricValue(*r, "process CPU utilization")
+	if okMachine && okProcess {
+		if others := machine - process/float64(runtime.NumCPU()); others >= anomalyNeighbours {
+			r.notes = append(r.notes, fmt.Sprintf("anomaly: other processes kept about %s%% of the machine busy", formatValue(others)))
+		}
+	}
+}
+
+// metricValue returns the value of the named metric of r.
+func metricValue(r result, name string) (float64, bool) {
+	for _, m := range r.metrics {
+		if m.name == name {
+			return m.value, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,22 @@
+package main
+
+import "time"
+
+// participant is what one goroutine of a contended scenario did, so that
+// unfairness between goroutines of the same role shows.
+type participant struct {
+	role     string
+	index    int
+	messages int
+	// blocked is the time spent waiting in channel operations, out of
+	// the elapsed time the goroutine ran for.
+	blocked time.Duration
+	elapsed time.Duration
+}
+
+func (p participant) throughput() float64 {
+	if p.elapsed <= 0 {
+		return 0
+	}
+	return float64(p.messages) / p.elapsed.Seconds()
+}
@@ -0,0 +1,47 @@
+package main
+
+import "flag"
+import "slices"
+import "time"
+
+var maxTotal = flag.Duration("max-total", 0, "fit the scenarios into this `duration`, running the most important first unless -shuffle is set, trimming repetitions and skipping what no longer fits")
+
+// priorityTags order the scenarios when -max-total is set: those with
+// the first tag run first, and those with none of them last. Scenarios
+// of equal priority keep their order, so that sweeps stay together, and
+// under -shuffle the order drawn stands.
+var priorityTags = []string{"micro", "latency", "macro"}
+
+func priority(s scenario) int {
+	for i, t := range priorityTags {
+		if slices.Contains(s.tags, t) {
+			return i
+		}
+	}
+	return len(priorityTags)
+}
+
+// budget shares what is left of -max-total among the scenarios still to
+// run. A nil budget is unlimited.
+type budget struct {
+	deadline time.Time
+}
+
+func newBudget(scenarios []scenario) *budget {
+	if *maxTotal <= 0 {
+		return nil
+	}
+	if !*shuffle {
+		slices.SortStableFunc(scenarios, func(a, b scenario) int { return priority(a) - priority(b) })
+	}
+	return &budget{time.Now().Add(*maxTotal)}
+}
+
+// allowance returns the time the next of left scenarios may take, which
+// is zero once the budget is spent, and -1 without a budget.
+func (b *budget) allowance(left int) time.Duration {
+	if b == nil {
+		return -1
+	}
+	return max(0, time.Until(b.deadline)/time.Duration(left))
+}
@@ -0,0 +1,78 @@
+package main
+
+import "slices"
+import "testing"
+import "time"
+
+func budgetNames(scenarios []scenario) []string {
+	names := make([]string, len(scenarios))
+	for i, s := range scenarios {
+		names[i] = s.name
+	}
+	return names
+}
+
+func budgetScenarios() []scenario {
+	return []scenario{
+		{name: "macro one", tags: tags("macro")},
+		{name: "untagged"},
+		{name: "micro one", tags: tags("micro")},
+		{name: "latency one", tags: tags("latency")},
+		{name: "micro two", tags: tags("micro", "latency")},
+	}
+}
+
+func setBudgetFlags(t *testing.T, total time.Duration, shuffling bool) {
+	savedTotal, savedShuffle := *maxTotal, *shuffle
+	t.Cleanup(func() { *maxTotal, *shuffle = savedTotal, savedShuffle })
+	*maxTotal, *shuffle = total, shuffling
+}
+
+func TestNewBudgetPriority(t *testing.T) {
+	setBudgetFlags(t, time.Minute, false)
+	scenarios := budgetScenarios()
+	if newBudget(scenarios) == nil {
+		t.Fatal("no budget with -max-total set")
+	}
+	want := []string{"micro one", "micro two", "latency one", "macro one", "untagged"}
+	if got := budgetNames(scenarios); !slices.Equal(got, want) {
+		t.Errorf("order %q, want %q", got, want)
+	}
+}
+
+func TestNewBudgetKeepsShuffle(t *testing.T) {
+	setBudgetFlags(t, time.Minute, true)
+	scenarios := budgetScenarios()
+	want := budgetNames(scenarios)
+	newBudget(scenarios)
+	if got := budgetNames(scenarios); !slices.Equal(got, want) {
+		t.Errorf("order %q, want the shuffled order %q", got, want)
+	}
+}
+
+func TestNewBudgetUnlimited(t *testing.T) {
+	setBudgetFlags(t, 0, false)
+	scenarios := budgetScenarios()
+	want := budgetNames(scenarios)
+	b := newBudget(scenarios)
+	if b != nil {
+		t.Error("a budget without -max-total")
+	}
+	if got := budgetNames(scenarios); !slices.Equal(got, want) {
+		t.Errorf("order %q, want %q", got, want)
+	}
+	if a := b.allowance(3); a != -1 {
+		t.Errorf("unlimited allowance %v, want -1", a)
+	}
+}
+
+func TestAllowance(t *testing.T) {
+	b := &budget{time.Now().Add(time.Hour)}
+	if a := b.allowance(4); a <= 14*time.Minute || a > 15*time.Minute {
+		t.Errorf("allowance of 4 scenarios in an hour %v, want about 15m", a)
+	}
+	spent := &budget{time.Now().Add(-time.Second)}
+	if a := spent.allowance(1); a != 0 {
+		t.Errorf("allowance of a spent budget %v, want 0", a)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import "flag"
+import "fmt"
+import "time"
+
+var within = flag.Float64("within", 5, "recommend the smallest channel capacity reaching within this `percent` of peak throughput")
+
+var sweepCapacities = []int{0, 1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024}
+
+func capacitySweep() []scenario {
+	var list []scenario
+	for _, c := range sweepCapacities {
+		list = append(list, scenario{fmt.Sprintf("capacity %d, two goroutines", c), streamWithCapacity(c), tags("scaling")})
+	}
+	return list
+}
+
+// streamWithCapacity streams from a producer to a consumer doing some
+// work per message, through a channel of the given capacity.
+func streamWithCapacity(capacity int) func(iterations int) result {
+	return func(iterations int) result {
+		ch := make(chan int, capacity)
+
+		then := time.Now()
+
+		go func() {
+			busy := 0
+			for i := 0; i < iterations; i++ {
+				busy += work(producerWork / 10)
+				ch <- i
+			}
+			close(ch)
+			sink(busy)
+		}()
+
+		busy := 0
+		for range ch {
+			busy += work(consumerWork / 10)
+		}
+		sink(busy)
+
+		return result{
+			messages: iterations,
+			elapsed:  time.Since(then),
+			sweep:    "capacity",
+			capacity: capacity,
+		}
+	}
+}
+
+// recommendation is the smallest capacity of a sweep whose throughput is
+// within some percentage of the best throughput measured.
+type recommendation struct {
+	sweep      string
+	capacity   int
+	throughput float64
+	peak       float64
+	within     float64
+}
+
+// capacityTuner collects the throughput measured at each capacity.
+type capacityTuner struct {
+	capacities  []int
+	throughputs []float64
+}
+
+func (t *capacityTuner) observe(r result) {
+	if r.sweep != "capacity" || r.elapsed <= 0 {
+		return
+	}
+	t.capacities = append(t.capacities, r.capacity)
+	t.throughputs = append(t.throughputs, float64(r.messages)/r.elapsed.Seconds())
+}
+
+func (t *capacityTuner) recommend() (recommendation, bool) {
+	if len(t.capacities) == 0 {
+		return recommendation{}, false
+	}
+	peak := 0.0
+	for _, v := range t.throughputs {
+		peak = max(peak, v)
+	}
+	best := -1
+	for i, v := range t.throughputs {
+		if v >= peak*(1-*within/100) && (best < 0 || t.capacities[i] < t.capacities[best]) {
+			best = i
+		}
+	}
+	return recommendation{"capacity", t.capacities[best], t.throughputs[best], peak, *within}, true
+}
@@ -1,103 +1,491 @@
 package main
 
+import "bytes"
+import "context"
+import "flag"
 import "fmt"
+import "io"
+import "math"
+import "os"
+import "os/signal"
+import "runtime"
+import "slices"
+import "strconv"
+import "syscall"
 import "time"
 
+// A scenario is one named measurement of moving a number of messages.
+type scenario struct {
+	name string
+	run  func(iterations int) result
+	// tags say what kind of measurement this is, such as micro or
+	// latency, so that suites can select from them.
+	tags []string
+}
+
+// result is what a scenario measured.
+type result struct {
+	// messages is the number of operations timed, usually the number
+	// of iterations requested.
+	messages int
+	elapsed  time.Duration
+	// firstMessage is the delay between starting the producer and the
+	// consumer receiving the first message. It is zero for scenarios
+	// that do not stream between goroutines.
+	firstMessage time.Duration
+	// metrics are any further quantities a scenario reports.
+	metrics []metric
+	// notes are remarks needed to read the numbers correctly.
+	notes []string
+	// sweep names the family of a result taken at one of several
+	// goroutine counts, given by parallelism, so that its speedup over
+	// the single goroutine case can be reported, or at one of several
+	// channel capacities, given by capacity.
+	sweep       string
+	parallelism int
+	capacity    int
+	// repetitions is the number of runs combined into this result, whose
+	// elapsed times are then means; samples has the time per message of
+	// every run, in nanoseconds.
+	repetitions int
+	samples     []float64
+	// participants break down the work of the producers, consumers or
+	// workers of a contended scenario, in its first run.
+	participants []participant
+	// err is why the scenario could not run; a result with an error has
+	// nothing else to report.
+	err error
+}
+
+// metric is a named quantity reported by a scenario, such as bytes
+// allocated per operation.
+type metric struct {
+	name  string
+	value float64
+	unit  string
+}
+
+var scenarios = slices.Concat([]scenario{
+	{"buffered(1) same goroutine", bufferedOneSync, tags("micro")},
+	{"buffered(1) two goroutines", bufferedOneAsync, tags("micro")},
+	{"unbuffered", unbuffered, tags("micro")},
+	{"unbuffered, GOMAXPROCS=1", unbufferedSingleP, tags("micro")},
+	{"semaphore rendezvous streaming", semStream, tags("micro", "baseline")},
+	{"buffered(N) same goroutine", bufferedNSync, tags("micro")},
+	{"buffered(N) two goroutines", bufferedNAsync, tags("micro")},
+	{"runtime.Gosched, alone", goschedAlone, tags("micro", "baseline")},
+	{"runtime.Gosched, two goroutines", goschedPair, tags("micro", "baseline")},
+	{"park/unpark round trip", parkRoundTrip, tags("micro", "baseline")},
+	{"double buffer, batch 16", doubleBuffer(16), tags("macro", "baseline")},
+	{"double buffer, batch 256", doubleBuffer(256), tags("macro", "baseline")},
+	{"double buffer, batch 4096", doubleBuffer(4096), tags("macro", "baseline")},
+	{"ping-pong, unbuffered", pingPong(0), tags("micro", "latency")},
+	{"ping-pong, capacity 1", pingPong(1), tags("micro", "latency")},
+	{"timestamps, unbuffered", timestampStream(0), tags("micro", "latency")},
+	{"timestamps, capacity 1", timestampStream(1), tags("micro", "latency")},
+	{"ping-pong, semaphore rendezvous", semPingPong, tags("micro", "latency", "baseline")},
+	{"ping-pong, spin exchange", spinPingPong(false), tags("micro", "latency", "baseline")},
+	{"ping-pong, spin exchange + Gosched", spinPingPong(true), tags("micro", "latency", "baseline")},
+	{"mixed payloads", mixedPayloads, tags("macro", "latency")},
+	{"1KB struct by value", largeByValue, tags("micro")},
+	{"1KB struct by pointer", largeByPointer, tags("micro")},
+	{"counted sends, adjacent counters", countedSends(false), tags("micro")},
+	{"counted sends, padded counters", countedSends(true), tags("micro")},
+	{"unbuffered among idle channels", idleOverhead, tags("micro")},
+	{"goroutine spawn + handoff", spawnHandoff, tags("micro")},
+	{"send stalls at overload", sendStalls, tags("macro", "latency")},
+	{"future via reply channel", futureChannel, tags("macro")},
+	{"future via callback", futureCallback, tags("macro", "baseline")},
+	{"future via WaitGroup", futureWaitGroup, tags("macro", "baseline")},
+	{"task queue, func() closures", closureTasks, tags("macro")},
+	{"task queue, data and switch", dataTasks, tags("macro")},
+	{"token ring", threadRing, tags("macro")},
+	{"prime sieve", primeSieve, tags("macro")},
+	{"daisy chain", daisyChain, tags("macro")},
+	{"shutdown, close and drain", closeAndDrain, tags("macro")},
+	{"shutdown, done channel and abandon", doneAndAbandon, tags("macro")},
+	{"receive, context.WithTimeout each", contextPerReceive, tags("micro")},
+	{"receive, time.After each", timeAfterReceive, tags("micro")},
+	{"receive, reused timer", reusedTimerReceive, tags("micro")},
+	{"relay, receive then send", forwardingRelay, tags("macro")},
+	{"relay, select send or receive", bufferingRelay, tags("macro")},
+	{"pipeline errors on error channel", pipelineErrorChannel, tags("macro")},
+	{"pipeline errors in-band", pipelineInBand, tags("macro")},
+}, payloadSweep, generatedPayloadScenarios, capacitySweep(), workerPoolScenarios(), pollingScenarios, rateLimitScenarios(), fanInScenarios(), lossyScenarios, poolShutdownScenarios(), lockScenarios(), actorScenarios(), makeChanScenarios())
+
+var format = flag.String("format", "text", "output `format`, text or json")
+
+var dbPath = flag.String("db", "", "also store results in the SQLite database at `path`, via the sqlite3 shell")
+
+var footprintK = flag.Int("footprint", 0, "also report the memory footprint of `K` channels of each capacity and element size")
+
+var openLoopCurve = flag.Bool("openloop", false, "also measure latency at increasing offered loads, up to saturation")
+
+var stressName = flag.String("stress", "", "run the `scenario` with this name until interrupted, printing live statistics")
+
+var shmScenario = flag.Bool("shm", false, "also stream through a shared-memory ring to a child process")
+
 func main() {
+	if spec := os.Getenv(shmRingEnv); spec != "" {
+		shmRingChild(spec)
+		return
+	}
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.Arg(0) == "compare" {
+		if flag.NArg() != 3 {
+			usage()
+			os.Exit(2)
+		}
+		if err := compare(os.Stdout, flag.Arg(1), flag.Arg(2)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "schema" {
+		fmt.Print(jsonSchema)
+		return
+	}
+
+	if flag.Arg(0) == "run-matrix" {
+		binaries, flags := flag.Args()[1:], []string(nil)
+		if i := slices.Index(binaries, "--"); i >= 0 {
+			binaries, flags = binaries[:i], binaries[i+1:]
+		}
+		if len(binaries) == 0 {
+			usage()
+			os.Exit(2)
+		}
+		if err := runMatrix(os.Stdout, binaries, flags); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *jobCost != "constant" && *jobCost != "uniform" && *jobCost != "exponential" {
+		fmt.Fprintf(os.Stderr, "unknown -jobcost distribution %q\n", *jobCost)
+		os.Exit(2)
+	}
+	if *noiseKind != "compute" && *noiseKind != "churn" {
+		fmt.Fprintf(os.Stderr, "unknown -noisekind %q\n", *noiseKind)
+		os.Exit(2)
+	}
+	if _, ok := suites[*suite]; !ok {
+		fmt.Fprintf(os.Stderr, "unknown -suite %q\n", *suite)
+		os.Exit(2)
+	}
+	if *leaks != "warn" && *leaks != "fail" && *leaks != "off" {
+		fmt.Fprintf(os.Stderr, "unknown -leaks mode %q\n", *leaks)
+		os.Exit(2)
+	}
+
+	if *shmScenario {
+		scenarios = append(scenarios, scenario{"shared-memory ring, two processes", shmRingStream, tags("macro", "baseline")})
+	}
+	if *topologyPairs {
+		scenarios = append(scenarios, topologyScenarios()...)
+	}
+
+	if *stressName != "" {
+		s, ok := findScenario(*stressName)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown scenario %q; scenarios are:\n", *stressName)
+			for _, s := range scenarios {
+				fmt.Fprintf(os.Stderr, "\t%s\n", s.name)
+			}
+			os.Exit(2)
+		}
+		stress(s)
+		return
+	}
+
+	if *raceCheck {
+		if !runRaceChecks() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := startMemProfiles(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	env := currentEnvironment()
+	// The dashboard redraws standard output in place, so the results are
+	// only written there once it is done.
+	var w io.Writer = os.Stdout
+	var deferred bytes.Buffer
+	if *tui {
+		w = &deferred
+	}
+	out, err := newOutput(*format, w, env)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *dbPath != "" {
+		db, err := newSQLiteOutput(*dbPath, env)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		out = multiOutput{out, db}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	scenarios = inSuite(scenarios, *suite)
+	if *shuffle {
+		scenarios = shuffled(scenarios)
+	}
+
+	var dash *dashboard
+	if *tui {
+		dash = newDashboard(os.Stdout, len(scenarios))
+		out = multiOutput{dash, out}
+	}
+
+	budget := newBudget(scenarios)
+	allocateBallast()
 	iterations := 120000
-	buflen := iterations/1000
 
+	// Scenarios run on their own goroutine, so that an interrupt can
+	// still report everything completed so far.
+	reports := make(chan func(output))
+	go func() {
+		speedups := make(speedups)
+		var tuner capacityTuner
+		for i, s := range scenarios {
+			allowance := budget.allowance(len(scenarios) - i)
+			if allowance == 0 {
+				var names []string
+				for _, s := range scenarios[i:] {
+					names = append(names, s.name)
+				}
+				reports <- func(o output) { o.skipped(names) }
+				break
+			}
+			dash.running(s.name)
+			r := repeat(s, iterations, allowance)
+			if r.err != nil {
+				reports <- func(o output) { o.failed(s.name, r.err) }
+				continue
+			}
+			speedups.annotate(&r)
+			tuner.observe(r)
+			reports <- func(o output) { o.result(s.name, r) }
+		}
+		if rec, ok := tuner.recommend(); ok {
+			reports <- func(o output) { o.recommendation(rec) }
+		}
+		if *footprintK > 0 {
+			list := footprints(*footprintK)
+			reports <- func(o output) { o.footprints(list) }
+		}
+		if *openLoopCurve {
+			curve := loadCurve()
+			reports <- func(o output) { o.loadCurve(curve) }
+		}
+		close(reports)
+	}()
+
+	for {
+		select {
+		case report, ok := <-reports:
+			if !ok {
+				out.finish(false)
+				os.Stdout.Write(deferred.Bytes())
+				if *leaks == "fail" && leaksFound > 0 {
+					fmt.Fprintf(os.Stderr, "%d scenarios leaked goroutines\n", leaksFound)
+					os.Exit(1)
+				}
+				if verifyFailures > 0 {
+					fmt.Fprintf(os.Stderr, "%d scenarios failed verification\n", verifyFailures)
+					os.Exit(1)
+				}
+				return
+			}
+			report(out)
+
+		case <-ctx.Done():
+			out.finish(true)
+			os.Stdout.Write(deferred.Bytes())
+			removeRingFiles()
+			os.Exit(130)
+		}
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: %[1]s [flags]\n       %[1]s compare base.json new.json\n       %[1]s run-matrix binary... [-- flags]\n       %[1]s schema\n\nflags:\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+// formatValue prints whole and large numbers without decimals, and
+// others with three significant digits.
+func formatValue(v float64) string {
+	if (v == math.Trunc(v) || math.Abs(v) >= 100) && math.Abs(v) < 1e15 {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'g', 3, 64)
+}
+
+// plural prints n followed by the noun, with an s unless n is one, as
+// in "1 caller" or "4 callers".
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func bufferedOneSync(iterations int) result {
 	buffered := make(chan int, 1)
+	check := newSequenceCheck(1, iterations)
 
 	then := time.Now()
 
-	for i:=0; i<iterations; i++ {
+	for i := 0; i < iterations; i++ {
 		buffered <- i
-		_ = <-buffered
+		check.receive(0, <-buffered)
 	}
 
 	close(buffered)
 
-	fmt.Print(time.Since(then))
-	fmt.Print("\t(")
-	fmt.Print(time.Since(then)/time.Duration(iterations))
-	fmt.Println(" per message)")
-
+	return check.verified(result{messages: iterations, elapsed: time.Since(then)})
+}
 
-	buffered = make(chan int, 1)
+func bufferedOneAsync(iterations int) result {
+	defer pinThread()()
+	buffered := make(chan int, 1)
+	check := newSequenceCheck(1, iterations)
+	cold := newColdSplit(iterations)
 
-	then = time.Now()
+	then := time.Now()
 
 	go func() {
-		for i:=0; i<iterations; i++ {
+		defer pinThread()()
+		for i := 0; i < iterations; i++ {
 			buffered <- i
 		}
 		close(buffered)
 	}()
 
-	for a := range(buffered) { _ = a }
-
-	fmt.Print(time.Since(then))
-	fmt.Print("\t(")
-	fmt.Print(time.Since(then)/time.Duration(iterations))
-	fmt.Println(" per message)")
+	var first time.Duration
+	for a := range buffered {
+		if first == 0 {
+			first = time.Since(then)
+		}
+		check.receive(0, a)
+		cold.receive(then)
+	}
 
+	return check.verified(cold.split(result{messages: iterations, elapsed: time.Since(then), firstMessage: first}))
+}
 
+func unbuffered(iterations int) result {
+	defer pinThread()()
 	unbuffered := make(chan int)
+	check := newSequenceCheck(1, iterations)
+	cold := newColdSplit(iterations)
 
-	then = time.Now()
+	then := time.Now()
 
 	go func() {
-		for i:=0; i<iterations; i++ {
+		defer pinThread()()
+		for i := 0; i < iterations; i++ {
 			unbuffered <- i
 		}
 		close(unbuffered)
 	}()
 
-	for a := range(unbuffered) { _ = a}
+	var first time.Duration
+	for a := range unbuffered {
+		if first == 0 {
+			first = time.Since(then)
+		}
+		check.receive(0, a)
+		cold.receive(then)
+	}
 
-	fmt.Print(time.Since(then))
-	fmt.Print("\t(")
-	fmt.Print(time.Since(then)/time.Duration(iterations))
-	fmt.Println(" per message)")
+	return check.verified(cold.split(result{messages: iterations, elapsed: time.Since(then), firstMessage: first}))
+}
 
+// unbufferedSingleP runs the unbuffered scenario on a single P, where
+// every handoff is a cooperative switch between goroutines on the same
+// thread rather than a wakeup of another P.
+func unbufferedSingleP(iterations int) result {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	return unbuffered(iterations)
+}
 
+func bufferedNSync(iterations int) result {
+	buflen := iterations / 1000
 	bufferedN := make(chan int, buflen)
+	check := newSequenceCheck(1, iterations)
 
-	then = time.Now()
-	for j:=0; j<(iterations/buflen); j++ {
+	then := time.Now()
+	for j := 0; j < (iterations / buflen); j++ {
 
-		for i:=0; i<buflen; i++ {
-			bufferedN <- i
+		for i := 0; i < buflen; i++ {
+			bufferedN <- j*buflen + i
 		}
 
-		for i:=0; i<buflen; i++ {
-			_ = <-bufferedN
+		for i := 0; i < buflen; i++ {
+			check.receive(0, <-bufferedN)
 		}
 	}
 	close(bufferedN)
 
-	fmt.Print(time.Since(then))
-	fmt.Print("\t(")
-	fmt.Print(time.Since(then)/time.Duration(iterations))
-	fmt.Println(" per message)")
-
+	return check.verified(result{messages: iterations, elapsed: time.Since(then)})
+}
 
-	bufferedN = make(chan int, buflen)
+func bufferedNAsync(iterations int) result {
+	defer pinThread()()
+	buflen := iterations / 1000
+	bufferedN := make(chan int, buflen)
+	check := newSequenceCheck(1, iterations)
+	cold := newColdSplit(iterations)
 
-	then = time.Now()
+	then := time.Now()
 	go func() {
-		for i:=0; i<iterations; i++ {
+		defer pinThread()()
+		for i := 0; i < iterations; i++ {
 			bufferedN <- i
 		}
 		close(bufferedN)
 	}()
 
-	for a := range(bufferedN) { _ = a}
+	var first time.Duration
+	for a := range bufferedN {
+		if first == 0 {
+			first = time.Since(then)
+		}
+		check.receive(0, a)
+		cold.receive(then)
+	}
 
-	fmt.Print(time.Since(then))
-	fmt.Print("\t(")
-	fmt.Print(time.Since(then)/time.Duration(iterations))
-	fmt.Println(" per message)")
+	return check.verified(cold.split(result{messages: iterations, elapsed: time.Since(then), firstMessage: first}))
 }
 
+// spawnHandoff starts one goroutine per message, which hands its value
+// over and exits; this is the cost paid by goroutine-per-request designs.
+func spawnHandoff(iterations int) result {
+	handoff := make(chan int)
+
+	then := time.Now()
+
+	for i := 0; i < iterations; i++ {
+		go func(i int) { handoff <- i }(i)
+		_ = <-handoff
+	}
+
+	return result{messages: iterations, elapsed: time.Since(then)}
+}
@@ -0,0 +1,41 @@
+package main
+
+import "time"
+
+// coldDivisor sets the share of a streaming scenario's messages reported
+// as its cold start: the first one in coldDivisor.
+const coldDivisor = 100
+
+// coldSplit times the cold start of a stream, which pays for creating
+// goroutines, faulting in channel buffers and warming caches and branch
+// predictors, apart from the steady state that follows.
+type coldSplit struct {
+	cold     int
+	received int
+	elapsed  time.Duration
+}
+
+func newColdSplit(iterations int) *coldSplit {
+	return &coldSplit{cold: max(1, iterations/coldDivisor)}
+}
+
+// receive counts a message received by a stream started at then.
+func (c *coldSplit) receive(then time.Time) {
+	c.received++
+	if c.received == c.cold {
+		c.elapsed = time.Since(then)
+	}
+}
+
+// split adds the time per message of the cold start and of the rest of
+// the stream to r.
+func (c *coldSplit) split(r result) result {
+	if c.received <= c.cold {
+		return r
+	}
+	r.metrics = append(r.metrics,
+		metric{"cold per message", float64(c.elapsed) / float64(c.cold), "ns"},
+		metric{"warm per message", float64(r.elapsed-c.elapsed) / float64(c.received-c.cold), "ns"},
+	)
+	return r
+}
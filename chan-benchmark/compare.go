@@ -0,0 +1,132 @@
+package main
+
+import "encoding/json"
+import "fmt"
+import "io"
+import "math"
+import "os"
+import "sort"
+
+// significance is the p-value below which a delta is marked significant.
+const significance = 0.05
+
+func readDocument(path string) (jsonDocument, error) {
+	var doc jsonDocument
+	f, err := os.Open(path)
+	if err != nil {
+		return doc, err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&doc); err != nil {
+		return doc, fmt.Errorf("%s: %v", path, err)
+	}
+	if doc.Schema > schemaVersion {
+		return doc, fmt.Errorf("%s: schema version %d is newer than %d", path, doc.Schema, schemaVersion)
+	}
+	return doc, nil
+}
+
+// compare prints the change in time per message of every scenario found
+// in both JSON result files, with the p-value of a Mann-Whitney U test
+// over their repetition samples.
+func compare(w io.Writer, basePath, newPath string) error {
+	base, err := readDocument(basePath)
+	if err != nil {
+		return err
+	}
+	current, err := readDocument(newPath)
+	if err != nil {
+		return err
+	}
+
+	baseline := make(map[string]jsonResult)
+	for _, r := range base.Results {
+		baseline[r.Name] = r
+	}
+
+	fmt.Fprintf(w, "%-34s %12s %12s %8s %8s\n", "scenario", "base ns/msg", "new ns/msg", "delta", "p")
+	for _, r := range current.Results {
+		b, ok := baseline[r.Name]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "%-34s %12s %12s", r.Name, formatValue(b.NsPerMessage), formatValue(r.NsPerMessage))
+		if delta, ok := percentChange(b.NsPerMessage, r.NsPerMessage); ok {
+			fmt.Fprintf(w, " %+7.1f%%", delta)
+		} else {
+			fmt.Fprintf(w, " %8s", "n/a")
+		}
+		if p, ok := mannWhitney(b.Samples, r.Samples); ok {
+			fmt.Fprintf(w, " %8.3g", p)
+			if p < significance {
+				fmt.Fprint(w, "  significant")
+			}
+		} else {
+			fmt.Fprintf(w, " %8s", "n/a")
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// percentChange returns the change from base to current in percent of
+// base, which is not available when base is zero.
+func percentChange(base, current float64) (float64, bool) {
+	if base == 0 {
+		return 0, false
+	}
+	return 100 * (current - base) / base, true
+}
+
+// mannWhitney returns the two-sided p-value of the Mann-Whitney U test
+// for samples a and b, using the normal approximation with a correction
+// for ties. It is not available when either sample has fewer than two
+// values, and is rough below about eight.
+func mannWhitney(a, b []float64) (float64, bool) {
+	n1, n2 := len(a), len(b)
+	if n1 < 2 || n2 < 2 {
+		return 0, false
+	}
+
+	type ranked struct {
+		value float64
+		first bool
+	}
+	all := make([]ranked, 0, n1+n2)
+	for _, v := range a {
+		all = append(all, ranked{v, true})
+	}
+	for _, v := range b {
+		all = append(all, ranked{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	// assign average ranks to runs of ties
+	var rankSum, ties float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].first {
+				rankSum += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	f1, f2 := float64(n1), float64(n2)
+	n := f1 + f2
+	u := rankSum - f1*(f1+1)/2
+	mean := f1 * f2 / 2
+	variance := f1 * f2 / 12 * ((n + 1) - ties/(n*(n-1)))
+	if variance <= 0 {
+		return 1, true
+	}
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	return math.Erfc(math.Max(z, 0) / math.Sqrt2), true
+}
@@ -0,0 +1,65 @@
+package main
+
+import "encoding/json"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+
+func TestPercentChange(t *testing.T) {
+	tests := []struct {
+		base, current float64
+		delta         float64
+		ok            bool
+	}{
+		{100, 150, 50, true},
+		{200, 100, -50, true},
+		{80, 80, 0, true},
+		{0, 10, 0, false},
+		{0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		delta, ok := percentChange(tt.base, tt.current)
+		if delta != tt.delta || ok != tt.ok {
+			t.Errorf("percentChange(%v, %v) = %v, %v; want %v, %v", tt.base, tt.current, delta, ok, tt.delta, tt.ok)
+		}
+	}
+}
+
+func TestMannWhitney(t *testing.T) {
+	if _, ok := mannWhitney([]float64{1}, []float64{1, 2}); ok {
+		t.Error("mannWhitney with a single sample is available")
+	}
+	if p, _ := mannWhitney([]float64{1, 2, 3, 4}, []float64{1, 2, 3, 4}); p < 0.5 {
+		t.Errorf("p = %v for identical samples, want at least 0.5", p)
+	}
+	a := []float64{10, 11, 12, 13, 14, 15, 16, 17}
+	b := []float64{20, 21, 22, 23, 24, 25, 26, 27}
+	if p, _ := mannWhitney(a, b); p >= significance {
+		t.Errorf("p = %v for disjoint samples, want below %v", p, significance)
+	}
+}
+
+func TestCompareZeroBaseline(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, ns float64) string {
+		doc := jsonDocument{Schema: schemaVersion, Results: []jsonResult{{Name: "scenario", NsPerMessage: ns}}}
+		data, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	var out strings.Builder
+	if err := compare(&out, write("base.json", 0), write("new.json", 12)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || strings.Contains(lines[1], "Inf") || strings.Contains(lines[1], "NaN") || !strings.Contains(lines[1], "n/a") {
+		t.Errorf("compare printed\n%s\nwant the delta as n/a", out.String())
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+// coreTime is the time one core spent busy, and the time the hypervisor
+// gave it to other guests, out of its total time.
+type coreTime struct {
+	busy, steal, total uint64
+}
+
+type coreTimes []coreTime
+
+// minCoreJiffies is the shortest window, in jiffies per core, over which
+// delta reports anything: /proc/stat counts in jiffies, 10 ms apiece at
+// the usual 100 Hz, so a shorter window rounds every core to 0 or 100%.
+const minCoreJiffies = 100
+
+// delta reports the average and the highest utilization of the cores
+// between the two snapshots, and the average share of their time stolen
+// by the hypervisor. This is for the whole machine, not only for this
+// process; it reports nothing over fewer than minCoreJiffies.
+func (c coreTimes) delta(later coreTimes) []metric {
+	if len(c) == 0 || len(c) != len(later) {
+		return nil
+	}
+	var sum, busiest, stolen float64
+	for i := range c {
+		total := later[i].total - c[i].total
+		if total < minCoreJiffies {
+			return nil
+		}
+		u := 100 * float64(later[i].busy-c[i].busy) / float64(total)
+		sum += u
+		busiest = max(busiest, u)
+		stolen += 100 * float64(later[i].steal-c[i].steal) / float64(total)
+	}
+	return []metric{
+		{"average core utilization", sum / float64(len(c)), "%"},
+		{"busiest core utilization", busiest, "%"},
+		{"average steal time", stolen / float64(len(c)), "%"},
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import "bufio"
+import "os"
+import "strconv"
+import "strings"
+
+// readCoreTimes returns the busy, stolen and total jiffies of every core,
+// from the per-cpu lines of /proc/stat.
+func readCoreTimes() coreTimes {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var cores coreTimes
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") || fields[0] == "cpu" {
+			continue
+		}
+		var c coreTime
+		for i, field := range fields[1:] {
+			v, _ := strconv.ParseUint(field, 10, 64)
+			switch i {
+			case 3, 4:
+				// idle and iowait
+			case 7:
+				c.steal += v
+			case 8, 9:
+				// guest and guest_nice are already counted in user and nice
+				continue
+			default:
+				c.busy += v
+			}
+			c.total += v
+		}
+		cores = append(cores, c)
+	}
+	return cores
+}
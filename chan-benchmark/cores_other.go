@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// readCoreTimes is not available on this platform.
+func readCoreTimes() coreTimes {
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestCoreTimesDelta(t *testing.T) {
+	before := coreTimes{{busy: 100, steal: 0, total: 1000}, {busy: 200, steal: 10, total: 1000}}
+	after := coreTimes{{busy: 150, steal: 0, total: 1200}, {busy: 350, steal: 30, total: 1200}}
+	got := before.delta(after)
+	want := []metric{
+		{"average core utilization", 50, "%"},
+		{"busiest core utilization", 75, "%"},
+		{"average steal time", 5, "%"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("delta = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("delta[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCoreTimesDeltaOmitted(t *testing.T) {
+	before := coreTimes{{busy: 100, total: 1000}, {busy: 100, total: 1000}}
+	tests := []struct {
+		name  string
+		later coreTimes
+	}{
+		{"no cores", nil},
+		{"cores differ", coreTimes{{busy: 200, total: 1200}}},
+		{"short window", coreTimes{{busy: 105, total: 1005}, {busy: 105, total: 1005}}},
+		{"one core short", coreTimes{{busy: 200, total: 1200}, {busy: 101, total: 1099}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := before.delta(tt.later); got != nil {
+				t.Errorf("delta = %v, want nothing", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package main
+
+import "time"
+
+// resourceUsage is the CPU time and context switches of the process.
+type resourceUsage struct {
+	cpu         time.Duration
+	voluntary   int64
+	involuntary int64
+}
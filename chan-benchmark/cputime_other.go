@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// readUsage is not available on this platform.
+func readUsage() resourceUsage {
+	return resourceUsage{}
+}
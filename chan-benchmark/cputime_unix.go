@@ -0,0 +1,19 @@
+//go:build unix
+
+package main
+
+import "syscall"
+import "time"
+
+// readUsage returns the resources used so far by the whole process.
+func readUsage() resourceUsage {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return resourceUsage{}
+	}
+	return resourceUsage{
+		cpu:         time.Duration(ru.Utime.Nano() + ru.Stime.Nano()),
+		voluntary:   int64(ru.Nvcsw),
+		involuntary: int64(ru.Nivcsw),
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "flag"
+import "time"
+
+var chainLength = flag.Int("chain", 10000, "number of goroutines in the daisy chain")
+
+// daisyChain builds a chain of goroutines, each adding one to the value
+// it receives before forwarding it, then sends one value down the chain.
+// Building the chain and propagating the value are timed separately, and
+// the stack memory of the built chain is reported.
+func daisyChain(iterations int) result {
+	n := max(1, *chainLength)
+	stacks := stackInUse()
+
+	then := time.Now()
+
+	leftmost := make(chan int)
+	right := leftmost
+	for i := 0; i < n; i++ {
+		left := right
+		right = make(chan int)
+		go func(left chan<- int, right <-chan int) {
+			left <- 1 + <-right
+		}(left, right)
+	}
+	built := time.Now()
+	stack := stackGrowth(stacks, n)
+
+	right <- 1
+	v := <-leftmost
+	propagated := time.Now()
+
+	if v != n+1 {
+		panic("daisy chain lost a hop")
+	}
+
+	return result{
+		messages: n,
+		elapsed:  propagated.Sub(then),
+		metrics: append([]metric{
+			{"construction", float64(built.Sub(then)), "ns"},
+			{"propagation", float64(propagated.Sub(built)), "ns"},
+		}, stack...),
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import "time"
+
+// doubleBuffer streams items in batches: the producer fills one buffer
+// while the consumer empties the other, and they swap buffers over a
+// pair of channels. Synchronization happens once per batch, rather than
+// once per item as in the streaming scenarios.
+func doubleBuffer(batch int) func(iterations int) result {
+	return func(iterations int) result {
+		full := make(chan []int, 1)
+		empty := make(chan []int, 2)
+		empty <- make([]int, 0, batch)
+		empty <- make([]int, 0, batch)
+
+		then := time.Now()
+
+		go func() {
+			buf := <-empty
+			for i := 0; i < iterations; i++ {
+				buf = append(buf, i)
+				if len(buf) == batch {
+					full <- buf
+					buf = <-empty
+				}
+			}
+			if len(buf) > 0 {
+				full <- buf
+			}
+			close(full)
+		}()
+
+		var first time.Duration
+		sum := 0
+		for buf := range full {
+			if first == 0 {
+				first = time.Since(then)
+			}
+			for _, v := range buf {
+				sum += v
+			}
+			empty <- buf[:0]
+		}
+
+		return result{messages: iterations, elapsed: time.Since(then), firstMessage: first}
+	}
+}
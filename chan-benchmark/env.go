@@ -0,0 +1,29 @@
+package main
+
+import "os"
+import "runtime"
+import "time"
+
+// environment describes the machine and runtime results were taken on.
+type environment struct {
+	Started    time.Time `json:"started"`
+	GoVersion  string    `json:"go_version"`
+	GOOS       string    `json:"goos"`
+	GOARCH     string    `json:"goarch"`
+	NumCPU     int       `json:"num_cpu"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	Hostname   string    `json:"hostname"`
+}
+
+func currentEnvironment() environment {
+	hostname, _ := os.Hostname()
+	return environment{
+		Started:    time.Now(),
+		GoVersion:  runtime.Version(),
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Hostname:   hostname,
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import "sync"
+import "sync/atomic"
+import "time"
+
+const sharingProducers = 4
+
+// cacheLine is the assumed size of a cache line.
+const cacheLine = 64
+
+type paddedCounter struct {
+	atomic.Uint64
+	_ [cacheLine - 8]byte
+}
+
+// countedSends has several producers send to one consumer, each one
+// bumping its own counter after every send. The counters live next to
+// each other, or padded to a cache line each.
+func countedSends(padded bool) func(iterations int) result {
+	return func(iterations int) result {
+		var adjacent [sharingProducers]atomic.Uint64
+		var spread [sharingProducers]paddedCounter
+		counter := func(p int) *atomic.Uint64 {
+			if padded {
+				return &spread[p].Uint64
+			}
+			return &adjacent[p]
+		}
+
+		ch := make(chan int, sharingProducers)
+		n := iterations / sharingProducers
+		check := newSequenceCheck(sharingProducers, n)
+
+		then := time.Now()
+
+		var wg sync.WaitGroup
+		wg.Add(sharingProducers)
+		for p := 0; p < sharingProducers; p++ {
+			go func(p int, c *atomic.Uint64) {
+				for i := 0; i < n; i++ {
+					ch <- p*n + i
+					c.Add(1)
+				}
+				wg.Done()
+			}(p, counter(p))
+		}
+		go func() {
+			wg.Wait()
+			close(ch)
+		}()
+
+		messages := 0
+		for v := range ch {
+			check.receive(v/n, v%n)
+			messages++
+		}
+
+		return check.verified(result{messages: messages, elapsed: time.Since(then)})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import "sync"
+import "time"
+
+// producerWork is the busy work done by each fan-in producer for every
+// message, so that adding producers can add throughput.
+const producerWork = 500
+
+var fanInProducers = []int{1, 2, 4, 8}
+
+func fanInScenarios() []scenario {
+	var list []scenario
+	for _, p := range fanInProducers {
+		list = append(list, scenario{"fan-in, " + plural(p, "producer"), fanIn(p), tags("macro", "scaling")})
+	}
+	return list
+}
+
+// fanIn returns a scenario where the given number of producers share
+// the work of producing messages for one consumer.
+func fanIn(producers int) func(iterations int) result {
+	return func(iterations int) result {
+		ch := make(chan int, producers)
+		n := iterations / producers
+		check := newSequenceCheck(producers, n)
+
+		then := time.Now()
+
+		participants := make([]participant, producers)
+		var wg sync.WaitGroup
+		wg.Add(producers)
+		for p := 0; p < producers; p++ {
+			go func(p int) {
+				start := time.Now()
+				var blocked time.Duration
+				busy := 0
+				for i := 0; i < n; i++ {
+					busy += work(producerWork)
+					t := time.Now()
+					ch <- p*n + i
+					blocked += time.Since(t)
+				}
+				participants[p] = participant{"producer", p, n, blocked, time.Since(start)}
+				sink(busy)
+				wg.Done()
+			}(p)
+		}
+		go func() {
+			wg.Wait()
+			close(ch)
+		}()
+
+		messages := 0
+		for v := range ch {
+			messages++
+			check.receive(v/n, v%n)
+		}
+
+		return check.verified(result{
+			messages:     messages,
+			elapsed:      time.Since(then),
+			sweep:        "fan-in",
+			parallelism:  producers,
+			participants: participants,
+		})
+	}
+}
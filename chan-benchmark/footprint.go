@@ -0,0 +1,54 @@
+package main
+
+import "fmt"
+import "runtime"
+import "unsafe"
+
+// footprint is the resident heap cost of one kind of channel.
+type footprint struct {
+	name     string
+	channels int
+	bytes    float64
+}
+
+func footprints(k int) []footprint {
+	var list []footprint
+	for _, capacity := range []int{0, 1, 64, 4096} {
+		list = append(list,
+			measureFootprint[[8]byte](k, capacity),
+			measureFootprint[[256]byte](k, capacity),
+			measureFootprint[[4096]byte](k, capacity),
+		)
+	}
+	return list
+}
+
+// measureFootprint allocates k channels of T with the given capacity,
+// fewer if their buffers would exceed makeChanBudget, and reports the
+// heap kept in use per channel once garbage has been collected.
+func measureFootprint[T any](k, capacity int) footprint {
+	var element T
+	if size := capacity * int(unsafe.Sizeof(element)); size > 0 {
+		k = min(k, max(1, makeChanBudget/size))
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	channels := make([]chan T, k)
+	for i := range channels {
+		channels[i] = make(chan T, capacity)
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(channels)
+
+	inUse := float64(int64(after.HeapInuse)-int64(before.HeapInuse)) / float64(k)
+	return footprint{
+		name:     fmt.Sprintf("make(chan [%d]byte, %d)", unsafe.Sizeof(element), capacity),
+		channels: k,
+		bytes:    inUse,
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import "runtime"
+import "sync"
+import "time"
+
+// A futureJob asks the worker for a result, delivered in one of three
+// ways: on a one-shot reply channel, through a callback, or in a shared
+// variable guarded by a WaitGroup.
+type futureJob struct {
+	value    int
+	reply    chan int
+	callback func(int)
+	shared   *int
+	wg       *sync.WaitGroup
+}
+
+func futureWorker(jobs <-chan futureJob) {
+	for j := range jobs {
+		v := j.value * 2
+		switch {
+		case j.reply != nil:
+			j.reply <- v
+		case j.callback != nil:
+			j.callback(v)
+		default:
+			*j.shared = v
+			j.wg.Done()
+		}
+	}
+}
+
+// futureScenario times iterations of request, which hands one job to
+// the worker and waits for its result.
+func futureScenario(iterations int, request func(jobs chan<- futureJob, i int), wait func()) result {
+	jobs := make(chan futureJob)
+	go futureWorker(jobs)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	then := time.Now()
+
+	for i := 0; i < iterations; i++ {
+		request(jobs, i)
+	}
+	wait()
+
+	elapsed := time.Since(then)
+	runtime.ReadMemStats(&after)
+	close(jobs)
+
+	return result{messages: iterations, elapsed: elapsed, metrics: allocations(&before, &after, iterations)}
+}
+
+func futureChannel(iterations int) result {
+	return futureScenario(iterations, func(jobs chan<- futureJob, i int) {
+		reply := make(chan int, 1)
+		jobs <- futureJob{value: i, reply: reply}
+		_ = <-reply
+	}, func() {})
+}
+
+// futureCallback does not wait for each result; the callbacks count
+// down a WaitGroup that is waited on once, at the end.
+func futureCallback(iterations int) result {
+	var wg sync.WaitGroup
+	wg.Add(iterations)
+	sum := 0
+	return futureScenario(iterations, func(jobs chan<- futureJob, i int) {
+		jobs <- futureJob{value: i, callback: func(v int) {
+			sum += v
+			wg.Done()
+		}}
+	}, wg.Wait)
+}
+
+func futureWaitGroup(iterations int) result {
+	return futureScenario(iterations, func(jobs chan<- futureJob, i int) {
+		var wg sync.WaitGroup
+		var v int
+		wg.Add(1)
+		jobs <- futureJob{value: i, shared: &v, wg: &wg}
+		wg.Wait()
+		_ = v
+	}, func() {})
+}
@@ -0,0 +1,130 @@
+package main
+
+import "math/rand"
+import "runtime/metrics"
+
+// generatedSize is the approximate size of every generated payload, so
+// that only their shape differs.
+const generatedSize = 1 << 10
+
+// payloadWindow is the number of recent payloads the consumer keeps, as
+// a cache or reorder buffer would, so that they are live when the
+// collector runs and it has to scan them.
+const payloadWindow = 1024
+
+// A payload is a message built by a payloadGenerator.
+type payload interface {
+	// weight reads the payload, returning a value derived from it.
+	weight() int
+}
+
+// A payloadGenerator builds the payloads of a generated payload scenario.
+type payloadGenerator interface {
+	next(i int) payload
+}
+
+var generatedPayloadScenarios = []scenario{
+	{"generated payload, constant", generatedPayload(func() payloadGenerator { return constantGenerator{new(bytesPayload)} }), tags("micro")},
+	{"generated payload, random bytes", generatedPayload(func() payloadGenerator { return randomGenerator{newRand()} }), tags("micro")},
+	{"generated payload, pointer graph", generatedPayload(func() payloadGenerator { return graphGenerator{} }), tags("micro")},
+}
+
+type bytesPayload [generatedSize]byte
+
+func (b *bytesPayload) weight() int { return int(b[0]) + int(b[len(b)-1]) }
+
+// constantGenerator sends the same payload every time, allocating nothing.
+type constantGenerator struct {
+	p *bytesPayload
+}
+
+func (g constantGenerator) next(i int) payload { return g.p }
+
+// randomGenerator allocates a fresh payload of random bytes, which the
+// collector never needs to scan.
+type randomGenerator struct {
+	rng *rand.Rand
+}
+
+func (g randomGenerator) next(i int) payload {
+	p := new(bytesPayload)
+	g.rng.Read(p[:])
+	return p
+}
+
+// graphNode is one object of a pointer-rich payload.
+type graphNode struct {
+	value       int
+	left, right *graphNode
+	parent      *graphNode
+	label       *string
+}
+
+// graphGenerator allocates a binary tree of graphNodes, about
+// generatedSize bytes in all, for the collector to trace.
+type graphGenerator struct{}
+
+var graphLabel = "node"
+
+func (g graphGenerator) next(i int) payload {
+	nodes := generatedSize / 40
+	root := &graphNode{value: i, label: &graphLabel}
+	queue := []*graphNode{root}
+	for n := 1; n < nodes; n++ {
+		parent := queue[0]
+		child := &graphNode{value: i + n, parent: parent, label: &graphLabel}
+		if parent.left == nil {
+			parent.left = child
+		} else {
+			parent.right = child
+			queue = queue[1:]
+		}
+		queue = append(queue, child)
+	}
+	return root
+}
+
+func (n *graphNode) weight() int {
+	if n == nil {
+		return 0
+	}
+	return n.value + n.left.weight() + n.right.weight()
+}
+
+const gcCycles = "/gc/cycles/total:gc-cycles"
+const gcCPU = "/cpu/classes/gc/total:cpu-seconds"
+
+func readGC() (cycles uint64, cpu float64) {
+	samples := []metrics.Sample{{Name: gcCycles}, {Name: gcCPU}}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		cycles = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindFloat64 {
+		cpu = samples[1].Value.Float64()
+	}
+	return cycles, cpu
+}
+
+// generatedPayload returns a scenario streaming the payloads of a fresh
+// generator to a consumer that keeps the latest payloadWindow of them,
+// and reports the garbage collection work they caused.
+func generatedPayload(generator func() payloadGenerator) func(iterations int) result {
+	return func(iterations int) result {
+		g := generator()
+		window := make([]payload, payloadWindow)
+		received := 0
+		cycles, cpu := readGC()
+		r := streamMessages(iterations, g.next, func(p payload) int {
+			window[received%payloadWindow] = p
+			received++
+			return p.weight()
+		})
+		laterCycles, laterCPU := readGC()
+		r.metrics = append(r.metrics,
+			metric{"GC cycles", float64(laterCycles - cycles), ""},
+			metric{"GC CPU per message", (laterCPU - cpu) * 1e9 / float64(r.messages), "ns"},
+		)
+		return r
+	}
+}
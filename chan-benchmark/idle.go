@@ -0,0 +1,56 @@
+package main
+
+import "flag"
+import "runtime"
+import "sync"
+
+var idleChannels = flag.Int("idle", 100000, "number of idle channels, each with a parked receiver, in the idle channels scenario")
+
+// idleOverhead runs the unbuffered hot path alone, then again while K
+// idle channels each keep a receiver parked, and reports the memory per
+// idle pair and the slowdown of the hot path.
+func idleOverhead(iterations int) result {
+	k := max(1, *idleChannels)
+	unbuffered(iterations) // warm up, so that both runs start alike
+	alone := unbuffered(iterations)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	channels := make([]chan int, k)
+	var parked sync.WaitGroup
+	var exited sync.WaitGroup
+	parked.Add(k)
+	exited.Add(k)
+	for i := range channels {
+		channels[i] = make(chan int)
+		go func(ch <-chan int) {
+			parked.Done()
+			<-ch
+			exited.Done()
+		}(channels[i])
+	}
+	parked.Wait()
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	r := unbuffered(iterations)
+
+	for _, ch := range channels {
+		close(ch)
+	}
+	exited.Wait()
+
+	heap := float64(after.HeapInuse) - float64(before.HeapInuse)
+	stacks := float64(after.StackInuse) - float64(before.StackInuse)
+	slowdown := 100 * (float64(r.elapsed) - float64(alone.elapsed)) / float64(alone.elapsed)
+	r.metrics = append(r.metrics,
+		metric{"idle pairs", float64(k), ""},
+		metric{"heap per idle pair", heap / float64(k), "B"},
+		metric{"stack per idle pair", stacks / float64(k), "B"},
+		metric{"hot path slowdown", slowdown, "%"},
+	)
+	return r
+}
@@ -0,0 +1,44 @@
+package main
+
+import "flag"
+import "fmt"
+import "os"
+import "runtime"
+import "time"
+
+var leaks = flag.String("leaks", "warn", "what to do when a scenario leaves goroutines behind: `mode` warn, fail or off")
+
+var leakStacks = flag.Bool("leakstacks", false, "dump all goroutine stacks when a leak is detected")
+
+// leakGrace is how long goroutines get to exit after a scenario returns
+// before they are counted as leaked.
+const leakGrace = 200 * time.Millisecond
+
+// leaksFound counts the scenarios that leaked, for -leaks=fail.
+var leaksFound int
+
+// checkLeaks compares the number of goroutines with the count taken
+// before the scenario ran, and notes any excess in the result.
+func checkLeaks(name string, before int, r *result) {
+	if *leaks == "off" {
+		return
+	}
+	deadline := time.Now().Add(leakGrace)
+	n := runtime.NumGoroutine()
+	for n > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		n = runtime.NumGoroutine()
+	}
+	if n <= before {
+		return
+	}
+
+	leaksFound++
+	r.metrics = append(r.metrics, metric{"leaked goroutines", float64(n - before), ""})
+	r.notes = append(r.notes, fmt.Sprintf("%d goroutines still running after the scenario; later results may be distorted", n-before))
+	fmt.Fprintf(os.Stderr, "%s: leaked %d goroutines\n", name, n-before)
+	if *leakStacks {
+		buf := make([]byte, 1<<20)
+		os.Stderr.Write(buf[:runtime.Stack(buf, true)])
+	}
+}
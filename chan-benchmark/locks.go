@@ -0,0 +1,90 @@
+package main
+
+import "fmt"
+import "sync"
+import "time"
+
+// lockWork is the busy work done inside every critical section.
+const lockWork = 20
+
+// lockWriteEvery is how often a read-mostly lock is acquired to write;
+// the other acquisitions only read.
+const lockWriteEvery = 10
+
+var lockContention = []int{1, 2, 8}
+
+// chanLock is a mutex made of a capacity-1 channel: sending acquires it
+// and receiving releases it.
+type chanLock chan struct{}
+
+func (l chanLock) Lock()   { l <- struct{}{} }
+func (l chanLock) Unlock() { <-l }
+
+func lockScenarios() []scenario {
+	// build returns the lock, and the read lock sharing it for the
+	// read-mostly kinds, or nil.
+	kinds := []struct {
+		name  string
+		build func() (sync.Locker, sync.Locker)
+		tags  []string
+	}{
+		{"channel", func() (sync.Locker, sync.Locker) { return make(chanLock, 1), nil }, tags("micro", "scaling")},
+		{"sync.Mutex", func() (sync.Locker, sync.Locker) { return new(sync.Mutex), nil }, tags("micro", "scaling", "baseline")},
+		{"RWMutex reads", func() (sync.Locker, sync.Locker) {
+			l := new(sync.RWMutex)
+			return l, l.RLocker()
+		}, tags("micro", "scaling", "baseline")},
+	}
+	var list []scenario
+	for _, k := range kinds {
+		for _, g := range lockContention {
+			list = append(list, scenario{fmt.Sprintf("lock, %s, %s", k.name, plural(g, "goroutine")), lockLoop(k.name, k.build, g), k.tags})
+		}
+	}
+	return list
+}
+
+// lockLoop returns a scenario where the given number of goroutines share
+// the acquisitions of one lock, each entering the critical section in
+// turn to update a shared counter. With a read lock, only one
+// acquisition in lockWriteEvery updates the counter, and the others hold
+// the read lock to read it.
+func lockLoop(name string, newLock func() (sync.Locker, sync.Locker), goroutines int) func(iterations int) result {
+	return func(iterations int) result {
+		l, rl := newLock()
+		counter := 0
+		n := iterations / goroutines
+
+		then := time.Now()
+
+		var wg sync.WaitGroup
+		wg.Add(goroutines)
+		for g := 0; g < goroutines; g++ {
+			go func() {
+				busy := 0
+				for i := 0; i < n; i++ {
+					if rl != nil && i%lockWriteEvery != 0 {
+						rl.Lock()
+						busy += work(lockWork) + counter
+						rl.Unlock()
+						continue
+					}
+					l.Lock()
+					busy += work(lockWork)
+					counter++
+					l.Unlock()
+				}
+				sink(busy)
+				wg.Done()
+			}()
+		}
+		wg.Wait()
+
+		return result{
+			messages:    goroutines * n,
+			elapsed:     time.Since(then),
+			sweep:       "lock, " + name,
+			parallelism: goroutines,
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import "fmt"
+import "os"
+import "sync"
+import "sync/atomic"
+import "time"
+
+// lossyCapacity is the size of the lossy event queues, which producers
+// keep full so that most events are dropped.
+const lossyCapacity = 64
+
+var lossyScenarios = []scenario{
+	{"lossy channel, drop newest", lossyChannel(false), tags("macro", "latency")},
+	{"lossy channel, overwrite oldest", lossyChannel(true), tags("macro", "latency")},
+	{"lossy ring, drop newest", lossyRing(false), tags("macro", "latency", "baseline")},
+	{"lossy ring, overwrite oldest", lossyRing(true), tags("macro", "latency", "baseline")},
+}
+
+// event is what the lossy queues carry: when it was sent, and its
+// number, p*n+i for event i of n from producer p, for -verify.
+type event struct {
+	sent time.Time
+	seq  int
+}
+
+// eventRing is a fixed-size queue guarded by a mutex, whose producers
+// never block: a full ring either refuses new events or overwrites its
+// oldest one.
+type eventRing struct {
+	mu     sync.Mutex
+	ready  *sync.Cond
+	events []event
+	head   int
+	count  int
+	closed bool
+}
+
+func newEventRing(capacity int) *eventRing {
+	r := &eventRing{events: make([]event, capacity)}
+	r.ready = sync.NewCond(&r.mu)
+	return r
+}
+
+// offer adds an event, and reports whether an event was dropped instead.
+func (r *eventRing) offer(e event, overwrite bool) (dropped bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.count == len(r.events) {
+		if !overwrite {
+			return true
+		}
+		r.head = (r.head + 1) % len(r.events)
+		r.count--
+		dropped = true
+	}
+	r.events[(r.head+r.count)%len(r.events)] = e
+	r.count++
+	r.ready.Signal()
+	return dropped
+}
+
+// take waits for the oldest event, and reports false once the ring is
+// closed and empty.
+func (r *eventRing) take() (event, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.count == 0 && !r.closed {
+		r.ready.Wait()
+	}
+	if r.count == 0 {
+		return event{}, false
+	}
+	e := r.events[r.head]
+	r.head = (r.head + 1) % len(r.events)
+	r.count--
+	return e, true
+}
+
+func (r *eventRing) close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	r.ready.Broadcast()
+}
+
+// lossyChannel returns a scenario where producers offer events to a
+// buffered channel with a non-blocking send, dropping either the new
+// event or, when overwrite is set, the oldest queued one. Evicting may
+// have to be retried when other producers refill the channel first, and
+// every event actually evicted counts as dropped.
+func lossyChannel(overwrite bool) func(iterations int) result {
+	return func(iterations int) result {
+		ch := make(chan event, lossyCapacity)
+		offer := func(e event) (dropped int) {
+			for {
+				select {
+				case ch <- e:
+					return dropped
+				default:
+				}
+				if !overwrite {
+					return 1
+				}
+				select {
+				case <-ch:
+					dropped++
+				default:
+				}
+			}
+		}
+		take := func() (event, bool) {
+			e, ok := <-ch
+			return e, ok
+		}
+		return lossyQueue(iterations, offer, take, func() { close(ch) })
+	}
+}
+
+// lossyRing returns the same scenario on a mutex-guarded ring.
+func lossyRing(overwrite bool) func(iterations int) result {
+	return func(iterations int) result {
+		r := newEventRing(lossyCapacity)
+		offer := func(e event) int {
+			if r.offer(e, overwrite) {
+				return 1
+			}
+			return 0
+		}
+		return lossyQueue(iterations, offer, r.take, r.close)
+	}
+}
+
+// lossyQueue has producers that outpace their consumer offer timestamped
+// events to a queue, and reports how many events were dropped and how
+// long the delivered ones waited. offer returns how many events it
+// dropped; delivered and dropped events must add up to those offered.
+func lossyQueue(iterations int, offer func(event) int, take func() (event, bool), done func()) result {
+	var drops atomic.Int64
+	n := iterations / overloadProducers
+	check := newSequenceCheck(overloadProducers, n)
+	check.allowGaps()
+
+	then := time.Now()
+
+	var wg sync.WaitGroup
+	wg.Add(overloadProducers)
+	for p := 0; p < overloadProducers; p++ {
+		go func(p int) {
+			for i := 0; i < n; i++ {
+				if dropped := offer(event{time.Now(), p*n + i}); dropped > 0 {
+					drops.Add(int64(dropped))
+				}
+			}
+			wg.Done()
+		}(p)
+	}
+	go func() {
+		wg.Wait()
+		done()
+	}()
+
+	var latencies []time.Duration
+	busy := 0
+	for {
+		e, ok := take()
+		if !ok {
+			break
+		}
+		latencies = append(latencies, time.Since(e.sent))
+		check.receive(e.seq/n, e.seq%n)
+		busy += work(consumerWork)
+	}
+	elapsed := time.Since(then)
+	sink(busy)
+
+	offered := overloadProducers * n
+	metrics := []metric{
+		{"delivered", float64(len(latencies)), ""},
+		{"drop rate", 100 * float64(drops.Load()) / float64(offered), "%"},
+	}
+	r := result{
+		messages: offered,
+		elapsed:  elapsed,
+		metrics:  append(metrics, distribution("latency", latencies)...),
+	}
+	if accounted := len(latencies) + int(drops.Load()); accounted != offered {
+		problem := fmt.Sprintf("delivered and dropped events add up to %d of %d offered", accounted, offered)
+		r.notes = append(r.notes, "accounting error: "+problem)
+		fmt.Fprintln(os.Stderr, "accounting error:", problem)
+	}
+	check.dropsReported(int(drops.Load()))
+	return check.verified(r)
+}
@@ -0,0 +1,56 @@
+package main
+
+import "fmt"
+import "runtime"
+import "time"
+import "unsafe"
+
+// makeChanBudget bounds the buffer memory allocated by one construction
+// scenario, so that the largest channels are built only a few times.
+const makeChanBudget = 256 << 20
+
+// chanSink keeps constructed channels reachable so that they escape.
+var chanSink any
+
+func makeChanScenarios() []scenario {
+	var list []scenario
+	for _, capacity := range []int{0, 1, 64, 4096} {
+		list = append(list,
+			scenario{fmt.Sprintf("make(chan [8]byte, %d)", capacity), makeChan[[8]byte](capacity), tags("micro")},
+			scenario{fmt.Sprintf("make(chan [256]byte, %d)", capacity), makeChan[[256]byte](capacity), tags("micro")},
+			scenario{fmt.Sprintf("make(chan [4096]byte, %d)", capacity), makeChan[[4096]byte](capacity), tags("micro")},
+		)
+	}
+	return list
+}
+
+// makeChan measures the construction of channels of T with the given
+// capacity, reporting the bytes allocated per construction.
+func makeChan[T any](capacity int) func(iterations int) result {
+	return func(iterations int) result {
+		var element T
+		n := iterations
+		if size := capacity * int(unsafe.Sizeof(element)); size > 0 {
+			n = min(n, max(1, makeChanBudget/size))
+		}
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+
+		then := time.Now()
+		for i := 0; i < n; i++ {
+			chanSink = make(chan T, capacity)
+		}
+		elapsed := time.Since(then)
+
+		runtime.ReadMemStats(&after)
+		chanSink = nil
+
+		allocated := float64(after.TotalAlloc-before.TotalAlloc) / float64(n)
+		return result{
+			messages: n,
+			elapsed:  elapsed,
+			metrics:  []metric{{"allocated", allocated, "B/op"}},
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import "bytes"
+import "encoding/json"
+import "fmt"
+import "io"
+import "os"
+import "os/exec"
+import "slices"
+
+// runMatrix runs every benchmark binary in turn with the given flags and
+// JSON output, then prints the time per message of each scenario under
+// every binary, with its change from the first one. The binaries are
+// typically this benchmark built with different Go versions or
+// GOEXPERIMENT settings.
+func runMatrix(w io.Writer, binaries, flags []string) error {
+	docs := make([]jsonDocument, len(binaries))
+	for i, bin := range binaries {
+		fmt.Fprintf(os.Stderr, "running %s\n", bin)
+		var stdout bytes.Buffer
+		cmd := exec.Command(bin, append(slices.Clone(flags), "-format", "json")...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %v", bin, err)
+		}
+		if err := json.Unmarshal(stdout.Bytes(), &docs[i]); err != nil {
+			return fmt.Errorf("%s: %v", bin, err)
+		}
+		if docs[i].Schema > schemaVersion {
+			return fmt.Errorf("%s: schema version %d is newer than %d", bin, docs[i].Schema, schemaVersion)
+		}
+	}
+
+	var names []string
+	perMessage := make([]map[string]float64, len(docs))
+	for i, doc := range docs {
+		perMessage[i] = make(map[string]float64)
+		for _, r := range doc.Results {
+			if _, ok := perMessage[0][r.Name]; !ok && !slices.Contains(names, r.Name) {
+				names = append(names, r.Name)
+			}
+			perMessage[i][r.Name] = r.NsPerMessage
+		}
+	}
+
+	for i, bin := range binaries {
+		fmt.Fprintf(w, "[%d] %s\n", i, bin)
+	}
+	fmt.Fprintf(w, "\n%-34s", "ns/msg")
+	for i := range binaries {
+		fmt.Fprintf(w, " %20s", fmt.Sprintf("[%d]", i))
+	}
+	fmt.Fprintln(w)
+	for _, name := range names {
+		fmt.Fprintf(w, "%-34s", name)
+		base, hasBase := perMessage[0][name]
+		for i := range binaries {
+			v, ok := perMessage[i][name]
+			switch {
+			case !ok:
+				fmt.Fprintf(w, " %20s", "-")
+			case i == 0 || !hasBase || base == 0:
+				fmt.Fprintf(w, " %20s", formatValue(v))
+			default:
+				fmt.Fprintf(w, " %20s", fmt.Sprintf("%s (%+.1f%%)", formatValue(v), 100*(v-base)/base))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import "flag"
+import "fmt"
+import "os"
+import "runtime"
+import "runtime/debug"
+import "time"
+
+var perf = flag.Bool("perf", false, "count cycles, instructions, cache misses and context switches per scenario (Linux only)")
+
+var gcOff = flag.Bool("gcoff", false, "disable the garbage collector while scenarios run")
+
+var settle = flag.Duration("settle", 10*time.Millisecond, "time to let the scheduler settle between scenarios, after a forced GC")
+
+// fullWindow is the share of the run, in percent, that the timed window
+// of a result must cover for the process CPU, context switches,
+// performance counters and core utilization, sampled around the whole
+// run, to be reported for that window. Scenarios that time only part of their run,
+// such as a sum of latencies or one of several phases, go without them.
+const fullWindow = 90
+
+// perfUnavailable is set once opening performance counters has failed,
+// so that the failure is only reported once.
+var perfUnavailable bool
+
+// measure runs one scenario, adding the runtime signals observed while
+// it ran to its result.
+func measure(s scenario, iterations int) result {
+	barrier()
+	goroutines := runtime.NumGoroutine()
+
+	sched := readSchedMetrics()
+	cores := readCoreTimes()
+	before := readUsage()
+	disturbed := readDisturbances()
+
+	var counters *perfCounters
+	if *perf && !perfUnavailable {
+		var err error
+		if counters, err = openPerfCounters(); err != nil {
+			fmt.Fprintf(os.Stderr, "no performance counters: %v\n", err)
+			perfUnavailable = true
+		}
+	}
+
+	gcPercent := 100
+	if *gcOff {
+		gcPercent = debug.SetGCPercent(-1)
+	}
+	stopNoise := startNoise()
+	start := time.Now()
+	r := s.run(iterations)
+	wall := time.Since(start)
+	stopNoise()
+	if *gcOff {
+		debug.SetGCPercent(gcPercent)
+	}
+
+	timed := r.messages > 0 && r.elapsed > 0 && r.elapsed >= wall*fullWindow/100
+	if counters != nil {
+		if m := counters.stop(r.messages); timed {
+			r.metrics = append(r.metrics, m...)
+		}
+	}
+
+	after := readUsage()
+	cpu := after.cpu - before.cpu
+	r.metrics = append(r.metrics, sched.delta(readSchedMetrics())...)
+	if cpu > 0 && timed {
+		r.metrics = append(r.metrics,
+			metric{"process CPU per message", float64(cpu) / float64(r.messages), "ns"},
+			metric{"process CPU utilization", 100 * float64(cpu) / float64(r.elapsed), "%"},
+			metric{"voluntary switches per message", float64(after.voluntary-before.voluntary) / float64(r.messages), ""},
+			metric{"involuntary switches per message", float64(after.involuntary-before.involuntary) / float64(r.messages), ""},
+		)
+	}
+	if timed {
+		r.metrics = append(r.metrics, cores.delta(readCoreTimes())...)
+	}
+	disturbed.annotate(readDisturbances(), before, after, wall, &r)
+	checkLeaks(s.name, goroutines, &r)
+	return r
+}
+
+// barrier brings each scenario to a comparable starting state: garbage is
+// collected (runtime.GC also finishes sweeping before it returns), then
+// background goroutines get the settle time to quiesce.
+func barrier() {
+	runtime.GC()
+	time.Sleep(*settle)
+}
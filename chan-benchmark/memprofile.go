@@ -0,0 +1,67 @@
+package main
+
+import "flag"
+import "fmt"
+import "os"
+import "path/filepath"
+import "runtime"
+import "runtime/pprof"
+import "strings"
+
+var memProfileDir = flag.String("memprofile", "", "write a heap profile to `dir` at the end of each scenario, or of each of its repetitions under -count")
+
+var memProfileGC = flag.Bool("memprofilegc", true, "collect garbage before writing each -memprofile heap profile, so that it shows live memory")
+
+var memProfileRate = flag.Int("memprofilerate", 0, "set runtime.MemProfileRate to `rate` bytes per sample; 1 records every allocation")
+
+// startMemProfiles creates the -memprofile directory and applies
+// -memprofilerate, before any scenario allocates.
+func startMemProfiles() error {
+	if *memProfileRate > 0 {
+		runtime.MemProfileRate = *memProfileRate
+	}
+	if *memProfileDir == "" {
+		return nil
+	}
+	return os.MkdirAll(*memProfileDir, 0o755)
+}
+
+// writeMemProfile writes the heap profile taken at the end of the given
+// repetition, counted from 1, of the named scenario; with -count above
+// 1, the file name carries the repetition. Its allocation counts are
+// totals since the benchmark started, so that those of one scenario are
+// its difference from the profile of the previous one, as shown by pprof
+// -diff_base.
+func writeMemProfile(name string, repetition int) {
+	if *memProfileDir == "" {
+		return
+	}
+	if *memProfileGC {
+		runtime.GC()
+	}
+	file := profileName(name)
+	if *count > 1 {
+		file += fmt.Sprintf(".%d", repetition)
+	}
+	path := filepath.Join(*memProfileDir, file+".heap.pprof")
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	defer f.Close()
+	if err := pprof.Lookup("heap").WriteTo(f, 0); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+	}
+}
+
+// profileName turns a scenario name into a file name.
+func profileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,74 @@
+package main
+
+import "flag"
+import "fmt"
+import "time"
+
+// mixCapacity is the capacity of the channel shared by the small and
+// large messages of the mixed payload scenario.
+const mixCapacity = 16
+
+var mixSmall, mixLarge = byteSize(16), byteSize(4 << 10)
+
+var mixPercent = flag.Float64("mixpercent", 5, "`percent` of large messages in the mixed payload scenario")
+
+func init() {
+	flag.Var(&mixSmall, "mixsmall", "`size` of the small messages in the mixed payload scenario")
+	flag.Var(&mixLarge, "mixlarge", "`size` of the large messages in the mixed payload scenario")
+}
+
+// sizedMessage is a payload of either class, stamped when it was sent.
+type sizedMessage struct {
+	sent    time.Time
+	payload []byte
+}
+
+// mixedPayloads interleaves small and large messages, chosen at random,
+// on one channel whose consumer reads every byte, and reports the latency
+// of each class separately: small messages queued behind large ones wait
+// for them to be built and read.
+func mixedPayloads(iterations int) result {
+	small, large := int(mixSmall), int(mixLarge)
+	source := make([]byte, max(small, large))
+	rng := newRand()
+	rng.Read(source)
+	ch := make(chan sizedMessage, mixCapacity)
+
+	then := time.Now()
+
+	go func() {
+		for i := 0; i < iterations; i++ {
+			size := small
+			if rng.Float64()*100 < *mixPercent {
+				size = large
+			}
+			payload := make([]byte, size)
+			copy(payload, source)
+			ch <- sizedMessage{time.Now(), payload}
+		}
+		close(ch)
+	}()
+
+	var smallLatencies, largeLatencies []time.Duration
+	bytes, sum := 0, 0
+	for m := range ch {
+		for _, b := range m.payload {
+			sum += int(b)
+		}
+		bytes += len(m.payload)
+		if len(m.payload) == large && large != small {
+			largeLatencies = append(largeLatencies, time.Since(m.sent))
+		} else {
+			smallLatencies = append(smallLatencies, time.Since(m.sent))
+		}
+	}
+	sink(sum)
+
+	r := result{messages: iterations, elapsed: time.Since(then)}
+	r.metrics = append(r.metrics, metric{"large messages", float64(len(largeLatencies)), ""})
+	r.metrics = append(r.metrics, distribution("small latency", smallLatencies)...)
+	r.metrics = append(r.metrics, distribution("large latency", largeLatencies)...)
+	r.metrics = append(r.metrics, metric{"bandwidth", float64(bytes) / r.elapsed.Seconds() / 1e6, "MB/s"})
+	r.notes = append(r.notes, fmt.Sprintf("%s%% of messages are %v, the others %v", formatValue(*mixPercent), &mixLarge, &mixSmall))
+	return r
+}
@@ -0,0 +1,42 @@
+package main
+
+import "flag"
+import "sync"
+import "sync/atomic"
+import "time"
+
+var noise = flag.Int("noise", 0, "run `N` unrelated busy goroutines while scenarios run")
+
+var noiseKind = flag.String("noisekind", "compute", "kind of -noise goroutines: `kind` compute, or churn to sleep and wake repeatedly")
+
+// startNoise starts the -noise goroutines and returns a function that
+// stops them and waits for them to exit.
+func startNoise() (stop func()) {
+	if *noise <= 0 {
+		return func() {}
+	}
+
+	var done atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(*noise)
+	for i := 0; i < *noise; i++ {
+		go func() {
+			defer wg.Done()
+			busy := 0
+			for !done.Load() {
+				if *noiseKind == "churn" {
+					time.Sleep(50 * time.Microsecond)
+					busy += work(100)
+				} else {
+					busy += work(10000)
+				}
+			}
+			sink(busy)
+		}()
+	}
+
+	return func() {
+		done.Store(true)
+		wg.Wait()
+	}
+}
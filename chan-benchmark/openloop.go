@@ -0,0 +1,73 @@
+package main
+
+import "time"
+
+// Open-loop runs offer messages at a fixed rate, whatever the speed of
+// the consumer, starting at openLoopStart messages per second and
+// doubling until the consumer saturates.
+const openLoopStart = 10000
+const openLoopSteps = 16
+const openLoopStep = 200 * time.Millisecond
+const openLoopCapacity = 1024
+
+// loadPoint is the latency observed at one offered rate.
+type loadPoint struct {
+	offered  float64
+	achieved float64
+	latency  []metric
+}
+
+// saturated reports whether the consumer fell behind the offered rate.
+func (p loadPoint) saturated() bool {
+	return p.achieved < 0.9*p.offered
+}
+
+func loadCurve() []loadPoint {
+	var curve []loadPoint
+	rate := float64(openLoopStart)
+	for i := 0; i < openLoopSteps; i++ {
+		p := openLoop(rate, openLoopStep)
+		curve = append(curve, p)
+		if p.saturated() {
+			break
+		}
+		rate *= 2
+	}
+	return curve
+}
+
+// openLoop offers messages at the given rate for the given duration.
+// Each message carries the time it was handed to the channel, so its
+// latency includes any time the send stayed blocked as well as queueing.
+func openLoop(rate float64, d time.Duration) loadPoint {
+	n := max(1, int(rate*d.Seconds()))
+	interval := time.Duration(float64(time.Second) / rate)
+	ch := make(chan time.Time, openLoopCapacity)
+
+	start := time.Now()
+	go func() {
+		for i := 0; i < n; i++ {
+			due := start.Add(time.Duration(i) * interval)
+			if wait := time.Until(due); wait > 0 {
+				time.Sleep(wait)
+			}
+			ch <- time.Now()
+		}
+		close(ch)
+	}()
+
+	latencies := make([]time.Duration, 0, n)
+	busy := 0
+	for sent := range ch {
+		busy += work(consumerWork)
+		latencies = append(latencies, time.Since(sent))
+	}
+	elapsed := time.Since(start)
+	sink(busy)
+
+	return loadPoint{
+		offered:  rate,
+		achieved: float64(n) / elapsed.Seconds(),
+		latency:  distribution("latency", latencies),
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import "encoding/json"
+import "fmt"
+import "io"
+import "math"
+import "os"
+import "time"
+
+// output writes scenario results in one of the supported formats.
+// finish is called once, after the last result or upon interruption,
+// in which case partial is true.
+type output interface {
+	result(name string, r result)
+	footprints(list []footprint)
+	loadCurve(curve []loadPoint)
+	recommendation(rec recommendation)
+	skipped(names []string)
+	failed(name string, err error)
+	finish(partial bool)
+}
+
+func newOutput(format string, w io.Writer, env environment) (output, error) {
+	switch format {
+	case "text":
+		return textOutput{w}, nil
+	case "json":
+		return &jsonOutput{w: w, doc: jsonDocument{Schema: schemaVersion, Environment: env}}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// perMessage is the elapsed time of r per message in nanoseconds, or zero
+// for a result without messages.
+func perMessage(r result) float64 {
+	if r.messages == 0 {
+		return 0
+	}
+	return float64(r.elapsed) / float64(r.messages)
+}
+
+// finite replaces the infinities and NaN that a ratio over an empty or
+// instantaneous run can give, which neither JSON nor SQL can represent,
+// with zero.
+func finite(v float64) float64 {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0
+	}
+	return v
+}
+
+// textOutput prints each result as soon as it is known.
+type textOutput struct {
+	w io.Writer
+}
+
+func (t textOutput) result(name string, r result) {
+	fmt.Fprintf(t.w, "%-34s %v\t(%v per message", name, r.elapsed, time.Duration(perMessage(r)))
+	if r.repetitions > 1 {
+		fmt.Fprintf(t.w, ", mean of %d", r.repetitions)
+	}
+	fmt.Fprint(t.w, ")")
+	if r.firstMessage > 0 {
+		fmt.Fprintf(t.w, "\t(first message after %v)", r.firstMessage)
+	}
+	for _, m := range r.metrics {
+		if m.unit == "" {
+			fmt.Fprintf(t.w, "\t(%s %s)", formatValue(m.value), m.name)
+		} else {
+			fmt.Fprintf(t.w, "\t(%s %s %s)", formatValue(m.value), m.unit, m.name)
+		}
+	}
+	fmt.Fprintln(t.w)
+	for _, p := range r.participants {
+		fmt.Fprintf(t.w, "%34s %s %d: %d messages, %v blocked of %v, %s msg/s\n",
+			"", p.role, p.index, p.messages, p.blocked, p.elapsed, formatValue(p.throughput()))
+	}
+	for _, n := range r.notes {
+		fmt.Fprintf(t.w, "%34s note: %s\n", "", n)
+	}
+}
+
+func (t textOutput) footprints(list []footprint) {
+	fmt.Fprintln(t.w)
+	fmt.Fprintln(t.w, "channel footprint (heap in use after GC)")
+	for _, f := range list {
+		fmt.Fprintf(t.w, "%-34s %d channels\t(%s B per channel)\n", f.name, f.channels, formatValue(f.bytes))
+	}
+}
+
+func (t textOutput) loadCurve(curve []loadPoint) {
+	fmt.Fprintln(t.w)
+	fmt.Fprintln(t.w, "open-loop latency under load")
+	for _, p := range curve {
+		fmt.Fprintf(t.w, "%10s msg/s offered %10s msg/s achieved", formatValue(p.offered), formatValue(p.achieved))
+		for _, m := range p.latency {
+			fmt.Fprintf(t.w, "\t(%s %s %s)", formatValue(m.value), m.unit, m.name)
+		}
+		if p.saturated() {
+			fmt.Fprint(t.w, "\tsaturated")
+		}
+		fmt.Fprintln(t.w)
+	}
+}
+
+func (t textOutput) recommendation(rec recommendation) {
+	fmt.Fprintf(t.w, "\nrecommended %s: %d, the smallest within %s%% of peak throughput (%s msg/s, peak %s msg/s)\n",
+		rec.sweep, rec.capacity, formatValue(rec.within), formatValue(rec.throughput), formatValue(rec.peak))
+}
+
+func (t textOutput) skipped(names []string) {
+	fmt.Fprintf(t.w, "\n-max-total left no time for %d scenarios:\n", len(names))
+	for _, n := range names {
+		fmt.Fprintf(t.w, "\t%s\n", n)
+	}
+}
+
+func (t textOutput) failed(name string, err error) {
+	fmt.Fprintf(t.w, "%-34s failed: %v\n", name, err)
+}
+
+func (t textOutput) finish(partial bool) {
+	if partial {
+		fmt.Fprintln(t.w, "interrupted: partial results")
+	}
+	fmt.Fprintf(t.w, "\nseed %d\n", *seed)
+}
+
+// jsonOutput collects results and writes them as one document.
+type jsonOutput struct {
+	w   io.Writer
+	doc jsonDocument
+}
+
+type jsonDocument struct {
+	Schema      int         `json:"schema"`
+	Environment environment `json:"environment"`
+
+	Partial    bool            `json:"partial,omitempty"`
+	Seed       int64           `json:"seed"`
+	Results    []jsonResult    `json:"results"`
+	Footprints []jsonFootprint `json:"footprints,omitempty"`
+	LoadCurve  []jsonLoadPoint `json:"load_curve,omitempty"`
+	// Recommendations are keyed by the name of the sweep they come from.
+	Recommendations map[string]jsonRecommendation `json:"recommendations,omitempty"`
+	// Skipped are the scenarios -max-total left no time for.
+	Skipped []string `json:"skipped,omitempty"`
+	// Failed are the scenarios that could not run, with the reason.
+	Failed []jsonFailure `json:"failed,omitempty"`
+}
+
+type jsonFailure struct {
+	Scenario string `json:"scenario"`
+	Error    string `json:"error"`
+}
+
+type jsonRecommendation struct {
+	Capacity      int     `json:"capacity"`
+	Throughput    float64 `json:"messages_per_second"`
+	Peak          float64 `json:"peak_messages_per_second"`
+	WithinPercent float64 `json:"within_percent"`
+}
+
+type jsonResult struct {
+	Name           string            `json:"name"`
+	Messages       int               `json:"messages"`
+	ElapsedNs      int64             `json:"elapsed_ns"`
+	NsPerMessage   float64           `json:"ns_per_message"`
+	FirstMessageNs int64             `json:"first_message_ns,omitempty"`
+	Estimator      string            `json:"estimator"`
+	Repetitions    int               `json:"repetitions"`
+	Samples        []float64         `json:"samples_ns_per_message,omitempty"`
+	Metrics        []jsonMetric      `json:"metrics,omitempty"`
+	Notes          []string          `json:"notes,omitempty"`
+	Participants   []jsonParticipant `json:"participants,omitempty"`
+}
+
+type jsonParticipant struct {
+	Role       string  `json:"role"`
+	Index      int     `json:"index"`
+	Messages   int     `json:"messages"`
+	BlockedNs  int64   `json:"blocked_ns"`
+	ElapsedNs  int64   `json:"elapsed_ns"`
+	Throughput float64 `json:"messages_per_second"`
+}
+
+type jsonMetric struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+type jsonFootprint struct {
+	Name            string  `json:"name"`
+	Channels        int     `json:"channels"`
+	BytesPerChannel float64 `json:"bytes_per_channel"`
+}
+
+type jsonLoadPoint struct {
+	Offered   float64      `json:"offered_per_second"`
+	Achieved  float64      `json:"achieved_per_second"`
+	Saturated bool         `json:"saturated"`
+	Latency   []jsonMetric `json:"latency"`
+}
+
+// jsonMetrics converts the metrics, leaving out those whose value is not
+// finite.
+func jsonMetrics(list []metric) []jsonMetric {
+	var metrics []jsonMetric
+	for _, m := range list {
+		if finite(m.value) == m.value {
+			metrics = append(metrics, jsonMetric{m.name, m.value, m.unit})
+		}
+	}
+	return metrics
+}
+
+func (j *jsonOutput) result(name string, r result) {
+	jr := jsonResult{
+		Name:           name,
+		Messages:       r.messages,
+		ElapsedNs:      r.elapsed.Nanoseconds(),
+		NsPerMessage:   perMessage(r),
+		FirstMessageNs: r.firstMessage.Nanoseconds(),
+		Estimator:      "single run",
+		Repetitions:    r.repetitions,
+		Samples:        r.samples,
+		Metrics:        jsonMetrics(r.metrics),
+		Notes:          r.notes,
+	}
+	for _, p := range r.participants {
+		jr.Participants = append(jr.Participants, jsonParticipant{p.role, p.index, p.messages, p.blocked.Nanoseconds(), p.elapsed.Nanoseconds(), finite(p.throughput())})
+	}
+	if r.repetitions > 1 {
+		jr.Estimator = "mean"
+	}
+	j.doc.Results = append(j.doc.Results, jr)
+}
+
+func (j *jsonOutput) footprints(list []footprint) {
+	for _, f := range list {
+		j.doc.Footprints = append(j.doc.Footprints, jsonFootprint{f.name, f.channels, finite(f.bytes)})
+	}
+}
+
+func (j *jsonOutput) loadCurve(curve []loadPoint) {
+	for _, p := range curve {
+		j.doc.LoadCurve = append(j.doc.LoadCurve, jsonLoadPoint{finite(p.offered), finite(p.achieved), p.saturated(), jsonMetrics(p.latency)})
+	}
+}
+
+func (j *jsonOutput) recommendation(rec recommendation) {
+	if j.doc.Recommendations == nil {
+		j.doc.Recommendations = make(map[string]jsonRecommendation)
+	}
+	j.doc.Recommendations[rec.sweep] = jsonRecommendation{rec.capacity, finite(rec.throughput), finite(rec.peak), finite(rec.within)}
+}
+
+func (j *jsonOutput) skipped(names []string) {
+	j.doc.Skipped = append(j.doc.Skipped, names...)
+}
+
+func (j *jsonOutput) failed(name string, err error) {
+	j.doc.Failed = append(j.doc.Failed, jsonFailure{name, err.Error()})
+}
+
+func (j *jsonOutput) finish(partial bool) {
+	j.doc.Partial = partial
+	j.doc.Seed = *seed
+	e := json.NewEncoder(j.w)
+	e.SetIndent("", "  ")
+	if err := e.Encode(j.doc); err != nil {
+		fmt.Fprintln(os.Stderr, "writing the JSON output:", err)
+	}
+}
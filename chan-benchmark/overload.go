@@ -0,0 +1,85 @@
+package main
+
+import "sync"
+import "sync/atomic"
+import "time"
+
+const overloadProducers = 4
+const overloadCapacity = 64
+
+// consumerWork is the busy work done by the overloaded consumer for
+// each message, so that producers always outpace it.
+const consumerWork = 200
+
+// workSink receives the results of busy work, so that it cannot be
+// optimized away. Each goroutine adds its own total once, when it is done.
+var workSink atomic.Int64
+
+// work does n steps of busy work and returns their result, to be summed
+// by the caller and passed to sink.
+func work(n int) int {
+	x := n
+	for i := 0; i < n; i++ {
+		x = x*31 + i
+	}
+	return x
+}
+
+func sink(x int) {
+	workSink.Add(int64(x))
+}
+
+// sendStalls keeps a buffered channel full with several producers that
+// outpace its consumer, and reports how long each send stays blocked.
+func sendStalls(iterations int) result {
+	ch := make(chan int, overloadCapacity)
+	stalls := make([][]time.Duration, overloadProducers)
+	participants := make([]participant, overloadProducers)
+	n := iterations / overloadProducers
+	check := newSequenceCheck(overloadProducers, n)
+
+	then := time.Now()
+
+	var wg sync.WaitGroup
+	wg.Add(overloadProducers)
+	for p := 0; p < overloadProducers; p++ {
+		go func(p int) {
+			start := time.Now()
+			samples := make([]time.Duration, 0, n)
+			var blocked time.Duration
+			for i := 0; i < n; i++ {
+				t := time.Now()
+				ch <- p*n + i
+				stall := time.Since(t)
+				samples = append(samples, stall)
+				blocked += stall
+			}
+			stalls[p] = samples
+			participants[p] = participant{"producer", p, n, blocked, time.Since(start)}
+			wg.Done()
+		}(p)
+	}
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	busy := 0
+	for v := range ch {
+		check.receive(v/n, v%n)
+		busy += work(consumerWork)
+	}
+	elapsed := time.Since(then)
+	sink(busy)
+
+	var all []time.Duration
+	for _, s := range stalls {
+		all = append(all, s...)
+	}
+	return check.verified(result{
+		messages:     len(all),
+		elapsed:      elapsed,
+		metrics:      distribution("send stall", all),
+		participants: participants,
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import "runtime"
+import "time"
+import "unsafe"
+
+// large is a 1KB message.
+type large struct {
+	words [128]int
+}
+
+// streamMessages streams iterations messages of type T from a producer,
+// which builds each one with build, and reports allocations.
+func streamMessages[T any](iterations int, build func(i int) T, read func(T) int) result {
+	ch := make(chan T)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	then := time.Now()
+
+	go func() {
+		for i := 0; i < iterations; i++ {
+			ch <- build(i)
+		}
+		close(ch)
+	}()
+
+	sum := 0
+	for v := range ch {
+		sum += read(v)
+	}
+
+	elapsed := time.Since(then)
+	runtime.ReadMemStats(&after)
+	return result{messages: iterations, elapsed: elapsed, metrics: allocations(&before, &after, iterations)}
+}
+
+// bandwidth is the payload throughput of a result whose messages each
+// carry the given number of bytes.
+func bandwidth(r result, bytes int) metric {
+	return metric{"bandwidth", float64(bytes) * float64(r.messages) / r.elapsed.Seconds() / 1e6, "MB/s"}
+}
+
+// streamPayload streams messages of the byte array type T by value, all
+// holding the same random bytes.
+func streamPayload[T any](iterations int) result {
+	var payload T
+	newRand().Read(unsafe.Slice((*byte)(unsafe.Pointer(&payload)), unsafe.Sizeof(payload)))
+	r := streamMessages(iterations, func(i int) T { return payload }, func(T) int { return 0 })
+	r.metrics = append(r.metrics, bandwidth(r, int(unsafe.Sizeof(payload))))
+	return r
+}
+
+var payloadSweep = []scenario{
+	{"payload 8B", streamPayload[[8]byte], tags("micro")},
+	{"payload 64B", streamPayload[[64]byte], tags("micro")},
+	{"payload 512B", streamPayload[[512]byte], tags("micro")},
+	{"payload 4KB", streamPayload[[4 << 10]byte], tags("micro")},
+	{"payload 32KB", streamPayload[[32 << 10]byte], tags("micro")},
+}
+
+// largeByValue copies the whole struct into and out of the channel.
+func largeByValue(iterations int) result {
+	r := streamMessages(iterations,
+		func(i int) large {
+			var l large
+			l.words[0] = i
+			return l
+		},
+		func(l large) int { return l.words[0] })
+	r.metrics = append(r.metrics, bandwidth(r, int(unsafe.Sizeof(large{}))))
+	return r
+}
+
+// largeByPointer sends a freshly allocated struct each time: the pointer
+// is cheap to copy but the struct escapes to the heap.
+func largeByPointer(iterations int) result {
+	r := streamMessages(iterations,
+		func(i int) *large {
+			l := new(large)
+			l.words[0] = i
+			return l
+		},
+		func(l *large) int { return l.words[0] })
+	r.notes = append(r.notes, "the sender must not touch a struct once sent; reusing one buffer instead of allocating would be a data race")
+	return r
+}
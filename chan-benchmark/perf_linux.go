@@ -0,0 +1,142 @@
+package main
+
+import "encoding/binary"
+import "errors"
+import "os"
+import "strconv"
+import "syscall"
+import "unsafe"
+
+// perfEventAttr is the first version of struct perf_event_attr, which
+// every kernel that has perf_event_open accepts.
+type perfEventAttr struct {
+	typ          uint32
+	size         uint32
+	config       uint64
+	samplePeriod uint64
+	sampleType   uint64
+	readFormat   uint64
+	flags        uint64
+	wakeupEvents uint32
+	bpType       uint32
+	config1      uint64
+}
+
+const (
+	perfTypeHardware = 0
+	perfTypeSoftware = 1
+
+	perfCountHWCPUCycles       = 0
+	perfCountHWInstructions    = 1
+	perfCountHWCacheMisses     = 3
+	perfCountSWContextSwitches = 3
+
+	perfFlagInherit       = 1 << 1
+	perfFlagExcludeKernel = 1 << 5
+	perfFlagExcludeHV     = 1 << 6
+)
+
+var perfEvents = []struct {
+	name   string
+	typ    uint32
+	config uint64
+	flags  uint64
+}{
+	{"cycles", perfTypeHardware, perfCountHWCPUCycles, perfFlagExcludeKernel | perfFlagExcludeHV},
+	{"instructions", perfTypeHardware, perfCountHWInstructions, perfFlagExcludeKernel | perfFlagExcludeHV},
+	{"cache misses", perfTypeHardware, perfCountHWCacheMisses, perfFlagExcludeKernel | perfFlagExcludeHV},
+	{"context switches", perfTypeSoftware, perfCountSWContextSwitches, 0},
+}
+
+// perfCounters holds one counter per event for every thread of the
+// process. Threads started while counting inherit their parent's
+// counters, whose counts are folded in when they exit.
+type perfCounters struct {
+	fds [][]int // by event, then by thread
+}
+
+func perfEventOpen(attr *perfEventAttr, tid int) (int, error) {
+	fd, _, errno := syscall.Syscall6(syscall.SYS_PERF_EVENT_OPEN,
+		uintptr(unsafe.Pointer(attr)), uintptr(tid), ^uintptr(0), ^uintptr(0), 0, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+func openPerfCounters() (*perfCounters, error) {
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return nil, err
+	}
+
+	p := &perfCounters{fds: make([][]int, len(perfEvents))}
+	for e, event := range perfEvents {
+		attr := perfEventAttr{
+			typ:    event.typ,
+			size:   uint32(unsafe.Sizeof(perfEventAttr{})),
+			config: event.config,
+			flags:  event.flags | perfFlagInherit,
+		}
+		for _, task := range tasks {
+			tid, err := strconv.Atoi(task.Name())
+			if err != nil {
+				continue
+			}
+			fd, err := perfEventOpen(&attr, tid)
+			if err == syscall.ESRCH {
+				continue // the thread has exited
+			}
+			if (err == syscall.ENOENT || err == syscall.EOPNOTSUPP) && len(p.fds[e]) == 0 {
+				break // this machine does not count this event
+			}
+			if err != nil {
+				p.close()
+				return nil, os.NewSyscallError("perf_event_open", err)
+			}
+			p.fds[e] = append(p.fds[e], fd)
+		}
+	}
+	if p.counting() == 0 {
+		return nil, errors.New("no supported events")
+	}
+	return p, nil
+}
+
+func (p *perfCounters) counting() int {
+	n := 0
+	for _, fds := range p.fds {
+		if len(fds) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// stop reads and closes the counters, reporting each event per message.
+func (p *perfCounters) stop(messages int) []metric {
+	var metrics []metric
+	buf := make([]byte, 8)
+	for e, fds := range p.fds {
+		if len(fds) == 0 {
+			continue
+		}
+		var total uint64
+		for _, fd := range fds {
+			if n, err := syscall.Read(fd, buf); err == nil && n == len(buf) {
+				total += binary.NativeEndian.Uint64(buf)
+			}
+		}
+		metrics = append(metrics, metric{perfEvents[e].name + " per message", float64(total) / float64(messages), ""})
+	}
+	p.close()
+	return metrics
+}
+
+func (p *perfCounters) close() {
+	for _, fds := range p.fds {
+		for _, fd := range fds {
+			syscall.Close(fd)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+type perfCounters struct{}
+
+func openPerfCounters() (*perfCounters, error) {
+	return nil, errors.New("hardware performance counters are only supported on Linux")
+}
+
+func (p *perfCounters) stop(messages int) []metric {
+	return nil
+}
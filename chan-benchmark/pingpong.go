@@ -0,0 +1,118 @@
+package main
+
+import "runtime"
+import "sync/atomic"
+import "time"
+
+// pingPong bounces a message between two goroutines over a pair of
+// channels with the given capacity; each iteration is one round trip,
+// and the one-way latency is half of it.
+func pingPong(capacity int) func(iterations int) result {
+	return func(iterations int) result {
+		ping := make(chan int, capacity)
+		pong := make(chan int, capacity)
+		check := newSequenceCheck(1, iterations)
+		go func() {
+			for v := range ping {
+				pong <- v
+			}
+			close(pong)
+		}()
+
+		then := time.Now()
+
+		for i := 0; i < iterations; i++ {
+			ping <- i
+			check.receive(0, <-pong)
+		}
+		elapsed := time.Since(then)
+
+		close(ping)
+		<-pong
+		return check.verified(result{
+			messages: iterations,
+			elapsed:  elapsed,
+			metrics:  []metric{{"one-way latency", float64(elapsed) / float64(2*iterations), "ns"}},
+		})
+	}
+}
+
+// timestampStream streams timestamps through a channel with the given
+// capacity and reports the distribution of one-way latencies.
+func timestampStream(capacity int) func(iterations int) result {
+	return func(iterations int) result {
+		ch := make(chan event, capacity)
+		latencies := make([]time.Duration, 0, iterations)
+		check := newSequenceCheck(1, iterations)
+
+		then := time.Now()
+
+		go func() {
+			for i := 0; i < iterations; i++ {
+				ch <- event{time.Now(), i}
+			}
+			close(ch)
+		}()
+
+		for e := range ch {
+			latencies = append(latencies, time.Since(e.sent))
+			check.receive(0, e.seq)
+		}
+
+		return check.verified(result{messages: iterations, elapsed: time.Since(then), metrics: distribution("one-way latency", latencies)})
+	}
+}
+
+// spinYieldEvery bounds how long a spinning side keeps its P before
+// yielding it, so that spinning terminates when both sides share a P.
+const spinYieldEvery = 1 << 12
+
+// A spinSlot is a single-slot exchange: the state tells which side may
+// touch the value next.
+type spinSlot struct {
+	state atomic.Int32
+	value int
+}
+
+const (
+	slotEmpty = iota
+	slotPing
+	slotPong
+)
+
+// await spins until the slot reaches the wanted state, yielding after
+// every poll when yield is set and after spinYieldEvery polls otherwise.
+func (s *spinSlot) await(want int32, yield bool) {
+	for n := 1; s.state.Load() != want; n++ {
+		if yield || n%spinYieldEvery == 0 {
+			runtime.Gosched()
+		}
+	}
+}
+
+// spinPingPong is the ping-pong scenario over a spinSlot, the latency
+// floor of a handoff when each side burns a core waiting.
+func spinPingPong(yield bool) func(iterations int) result {
+	return func(iterations int) result {
+		var slot spinSlot
+		check := newSequenceCheck(1, iterations)
+		go func() {
+			for i := 0; i < iterations; i++ {
+				slot.await(slotPing, yield)
+				slot.value++
+				slot.state.Store(slotPong)
+			}
+		}()
+
+		then := time.Now()
+
+		for i := 0; i < iterations; i++ {
+			slot.value = i
+			slot.state.Store(slotPing)
+			slot.await(slotPong, yield)
+			check.receive(0, slot.value-1)
+		}
+
+		return check.verified(result{messages: iterations, elapsed: time.Since(then)})
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import "errors"
+import "sync"
+import "time"
+
+// pipelineStages is the number of processing stages between the source
+// and the sink; each one fails on one message in errorEvery.
+const pipelineStages = 3
+const errorEvery = 100
+
+var errStage = errors.New("stage failed")
+
+// failsInStage reports whether one of the stages fails on message v.
+func failsInStage(v int) bool {
+	for s := 0; s < pipelineStages; s++ {
+		if (v+s)%errorEvery == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// pipelineErrorChannel runs a pipeline whose stages report failures on
+// their own error channel, merged into one stream by a collector.
+func pipelineErrorChannel(iterations int) result {
+	source := make(chan int)
+	errs := make([]chan error, pipelineStages)
+	check := newSequenceCheck(1, iterations)
+	check.allowDrops(failsInStage)
+
+	then := time.Now()
+
+	go func() {
+		for i := 0; i < iterations; i++ {
+			source <- i
+		}
+		close(source)
+	}()
+
+	in := source
+	for s := 0; s < pipelineStages; s++ {
+		out := make(chan int)
+		errs[s] = make(chan error)
+		go func(s int, in <-chan int, out chan<- int, errs chan<- error) {
+			for v := range in {
+				if (v+s)%errorEvery == 0 {
+					errs <- errStage
+					continue
+				}
+				out <- v
+			}
+			close(out)
+			close(errs)
+		}(s, in, out, errs[s])
+		in = out
+	}
+
+	merged := make(chan error)
+	var wg sync.WaitGroup
+	wg.Add(len(errs))
+	for _, e := range errs {
+		go func(e <-chan error) {
+			for err := range e {
+				merged <- err
+			}
+			wg.Done()
+		}(e)
+	}
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	failures := 0
+	done := make(chan struct{})
+	go func() {
+		for range merged {
+			failures++
+		}
+		close(done)
+	}()
+
+	var first time.Duration
+	for v := range in {
+		if first == 0 {
+			first = time.Since(then)
+		}
+		check.receive(0, v)
+	}
+	<-done
+	check.dropsReported(failures)
+
+	return check.verified(result{messages: iterations, elapsed: time.Since(then), firstMessage: first, metrics: []metric{{"failed messages", float64(failures), ""}}})
+}
+
+// outcome carries either a value or the error that replaced it.
+type outcome struct {
+	value int
+	err   error
+}
+
+// pipelineInBand runs the same pipeline with failures carried in-band,
+// so every stage forwards a single stream of outcomes.
+func pipelineInBand(iterations int) result {
+	source := make(chan outcome)
+	check := newSequenceCheck(1, iterations)
+
+	then := time.Now()
+
+	go func() {
+		for i := 0; i < iterations; i++ {
+			source <- outcome{value: i}
+		}
+		close(source)
+	}()
+
+	in := source
+	for s := 0; s < pipelineStages; s++ {
+		out := make(chan outcome)
+		go func(s int, in <-chan outcome, out chan<- outcome) {
+			for o := range in {
+				if o.err == nil && (o.value+s)%errorEvery == 0 {
+					o.err = errStage
+				}
+				out <- o
+			}
+			close(out)
+		}(s, in, out)
+		in = out
+	}
+
+	failures := 0
+	var first time.Duration
+	for o := range in {
+		if first == 0 {
+			first = time.Since(then)
+		}
+		if o.err != nil {
+			failures++
+		}
+		check.receive(0, o.value)
+	}
+
+	return check.verified(result{messages: iterations, elapsed: time.Since(then), firstMessage: first, metrics: []metric{{"failed messages", float64(failures), ""}}})
+}
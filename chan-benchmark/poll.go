@@ -0,0 +1,77 @@
+package main
+
+import "runtime"
+import "time"
+
+// Polling scenarios send far fewer messages than the others, paced at
+// pollInterval, so that the consumer is mostly waiting.
+const pollDivisor = 1000
+const pollInterval = 20 * time.Microsecond
+
+// pacedProducer sends n timestamps, pausing between each one.
+func pacedProducer(n int) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	go func() {
+		for i := 0; i < n; i++ {
+			ch <- time.Now()
+			time.Sleep(pollInterval)
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// pollResult reports message latency; the CPU time burned while waiting
+// for paced messages is reported for every scenario by measure.
+func pollResult(latencies []time.Duration, elapsed time.Duration) result {
+	return result{messages: len(latencies), elapsed: elapsed, metrics: distribution("latency", latencies)}
+}
+
+func blockingReceive(iterations int) result {
+	n := max(1, iterations/pollDivisor)
+	latencies := make([]time.Duration, 0, n)
+
+	then := time.Now()
+
+	for t := range pacedProducer(n) {
+		latencies = append(latencies, time.Since(t))
+	}
+
+	return pollResult(latencies, time.Since(then))
+}
+
+// pollingReceive returns a scenario whose consumer polls with a
+// select/default, calling pause (if any) after each empty poll.
+func pollingReceive(pause func()) func(iterations int) result {
+	return func(iterations int) result {
+		n := max(1, iterations/pollDivisor)
+		latencies := make([]time.Duration, 0, n)
+
+		then := time.Now()
+
+		ch := pacedProducer(n)
+	poll:
+		for {
+			select {
+			case t, ok := <-ch:
+				if !ok {
+					break poll
+				}
+				latencies = append(latencies, time.Since(t))
+			default:
+				if pause != nil {
+					pause()
+				}
+			}
+		}
+
+		return pollResult(latencies, time.Since(then))
+	}
+}
+
+var pollingScenarios = []scenario{
+	{"blocking receive", blockingReceive, tags("latency")},
+	{"polling receive (spin)", pollingReceive(nil), tags("latency")},
+	{"polling receive (Gosched)", pollingReceive(runtime.Gosched), tags("latency")},
+	{"polling receive (sleep 1µs)", pollingReceive(func() { time.Sleep(time.Microsecond) }), tags("latency")},
+}
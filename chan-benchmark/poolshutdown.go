@@ -0,0 +1,109 @@
+package main
+
+import "context"
+import "fmt"
+import "sync"
+import "time"
+
+// Every pool shutdown starts a fresh pool, so a scenario runs one per
+// shutdownDivisor iterations, each job costing shutdownJobWork.
+const shutdownDivisor = 200
+const shutdownJobWork = 1000
+
+var shutdownWorkers = []int{1, 4, 16}
+var shutdownDepths = []int{0, 64}
+
+func poolShutdownScenarios() []scenario {
+	var list []scenario
+	for _, cancel := range []bool{false, true} {
+		signal := "close"
+		if cancel {
+			signal = "cancel"
+		}
+		for _, w := range shutdownWorkers {
+			for _, d := range shutdownDepths {
+				list = append(list, scenario{fmt.Sprintf("shutdown, %s, W=%d, depth %d", signal, w, d), poolShutdown(w, d, cancel), tags("macro", "latency")})
+			}
+		}
+	}
+	return list
+}
+
+// poolShutdown returns a scenario that repeatedly starts a pool of
+// workers, and gives them one job each, which they hold until released,
+// and depth more queued. It then signals shutdown and releases the held
+// jobs: by closing the job channel, which lets the workers finish the
+// queued jobs, or when cancel is set by cancelling their context, which
+// makes them abandon those. Its elapsed time is the total of the
+// shutdown latencies, from the signal until every worker has exited and
+// the results channel has been drained.
+func poolShutdown(workers, depth int, cancel bool) func(iterations int) result {
+	return func(iterations int) result {
+		n := max(1, iterations/shutdownDivisor)
+		latencies := make([]time.Duration, 0, n)
+		abandoned := 0
+
+		for i := 0; i < n; i++ {
+			ctx, stop := context.WithCancel(context.Background())
+			jobs := make(chan int, depth)
+			results := make(chan int, workers+depth)
+			release := make(chan struct{})
+
+			var wg sync.WaitGroup
+			wg.Add(workers)
+			for w := 0; w < workers; w++ {
+				go func() {
+					defer wg.Done()
+					busy := 0
+					defer func() { sink(busy) }()
+					for {
+						select {
+						case <-ctx.Done():
+							return
+						case j, ok := <-jobs:
+							if !ok {
+								return
+							}
+							<-release
+							busy += work(shutdownJobWork)
+							results <- j
+						}
+					}
+				}()
+			}
+			for j := 0; j < workers+depth; j++ {
+				jobs <- j
+			}
+
+			then := time.Now()
+			if cancel {
+				stop()
+			} else {
+				close(jobs)
+			}
+			close(release)
+			wg.Wait()
+			close(results)
+			done := 0
+			for range results {
+				done++
+			}
+			latencies = append(latencies, time.Since(then))
+
+			abandoned += workers + depth - done
+			stop()
+		}
+
+		var total time.Duration
+		for _, l := range latencies {
+			total += l
+		}
+		metrics := []metric{{"abandoned jobs per shutdown", float64(abandoned) / float64(n), ""}}
+		return result{
+			messages: n,
+			elapsed:  total,
+			metrics:  append(metrics, distribution("shutdown latency", latencies)...),
+			notes:    []string{"each message is one shutdown of the pool"},
+		}
+	}
+}
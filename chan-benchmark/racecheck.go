@@ -0,0 +1,202 @@
+package main
+
+import "errors"
+import "flag"
+import "fmt"
+import "os"
+import "sync"
+import "sync/atomic"
+import "time"
+
+var raceCheck = flag.Bool("race-check", false, "instead of benchmarking, exercise the hand-written synchronization under heavy concurrency and report pass or fail for each; build with -race to also catch data races")
+
+// raceCheckGoroutines and raceCheckMessages size the checks: short, but
+// with many more goroutines than a benchmark scenario uses.
+const raceCheckGoroutines = 8
+const raceCheckMessages = 20000
+
+// raceCheckTimeout bounds how long a check waits for messages, so that
+// losing one fails the check rather than hanging it.
+const raceCheckTimeout = 10 * time.Second
+
+var errUnsupported = errors.New("not supported on this platform")
+
+var raceChecks = []struct {
+	name  string
+	check func() error
+}{
+	{"semaphore rendezvous", semChanCheck},
+	{"spin exchange", spinSlotCheck},
+	{"mutex ring, drop newest", eventRingCheck(false)},
+	{"mutex ring, overwrite oldest", eventRingCheck(true)},
+	{"channel lock", chanLockCheck},
+	{"SPSC shared-memory ring", func() error { return shmRingCheck(raceCheckMessages) }},
+}
+
+// runRaceChecks runs every check and reports whether all of them passed.
+// A data race found by the race detector makes the process exit with a
+// failure status once it is done, even when every check passed.
+func runRaceChecks() bool {
+	fmt.Printf("race detector enabled: %v\n", raceEnabled)
+	passed := true
+	for _, c := range raceChecks {
+		err := c.check()
+		switch {
+		case errors.Is(err, errUnsupported):
+			fmt.Printf("SKIP  %s: %v\n", c.name, err)
+		case err != nil:
+			fmt.Printf("FAIL  %s: %v\n", c.name, err)
+			passed = false
+		default:
+			fmt.Printf("PASS  %s\n", c.name)
+		}
+	}
+	if !passed {
+		fmt.Fprintln(os.Stderr, "race check failed")
+	}
+	return passed
+}
+
+// semChanCheck has several producers and consumers share one semChan,
+// and checks that every value is received exactly once.
+func semChanCheck() error {
+	c := newSemChan()
+	var producers, consumers sync.WaitGroup
+	var received, sum atomic.Int64
+	producers.Add(raceCheckGoroutines)
+	consumers.Add(raceCheckGoroutines)
+	for g := 0; g < raceCheckGoroutines; g++ {
+		go func() {
+			for i := 1; i <= raceCheckMessages/raceCheckGoroutines; i++ {
+				c.put(i)
+			}
+			producers.Done()
+		}()
+		go func() {
+			for {
+				v, ok := c.get()
+				if !ok {
+					break
+				}
+				received.Add(1)
+				sum.Add(int64(v))
+			}
+			consumers.Done()
+		}()
+	}
+	producers.Wait()
+	deadline := time.Now().Add(raceCheckTimeout)
+	for received.Load() < raceCheckMessages {
+		if time.Now().After(deadline) {
+			c.close()
+			return fmt.Errorf("received %d of %d messages within %v", received.Load(), raceCheckMessages, raceCheckTimeout)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.close()
+	consumers.Wait()
+
+	n := raceCheckMessages / raceCheckGoroutines
+	if want := int64(raceCheckGoroutines * n * (n + 1) / 2); sum.Load() != want {
+		return fmt.Errorf("received values summing to %d, sent %d", sum.Load(), want)
+	}
+	return nil
+}
+
+// spinSlotCheck runs several spin exchanges at once, and checks that each
+// reply answers the message it was sent for.
+func spinSlotCheck() error {
+	errs := make(chan error, raceCheckGoroutines)
+	for g := 0; g < raceCheckGoroutines; g++ {
+		go func() {
+			var slot spinSlot
+			n := raceCheckMessages / raceCheckGoroutines
+			go func() {
+				for i := 0; i < n; i++ {
+					slot.await(slotPing, true)
+					slot.value++
+					slot.state.Store(slotPong)
+				}
+			}()
+			for i := 0; i < n; i++ {
+				slot.value = i
+				slot.state.Store(slotPing)
+				slot.await(slotPong, true)
+				if slot.value != i+1 {
+					errs <- fmt.Errorf("reply %d to message %d", slot.value, i)
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+	var err error
+	for g := 0; g < raceCheckGoroutines; g++ {
+		err = errors.Join(err, <-errs)
+	}
+	return err
+}
+
+// eventRingCheck has several producers and consumers share one
+// eventRing, and checks that every event was either delivered or dropped.
+func eventRingCheck(overwrite bool) func() error {
+	return func() error {
+		r := newEventRing(lossyCapacity)
+		var producers, consumers sync.WaitGroup
+		var delivered, dropped atomic.Int64
+		producers.Add(raceCheckGoroutines)
+		consumers.Add(raceCheckGoroutines)
+		for g := 0; g < raceCheckGoroutines; g++ {
+			go func() {
+				for i := 0; i < raceCheckMessages/raceCheckGoroutines; i++ {
+					if r.offer(event{sent: time.Now()}, overwrite) {
+						dropped.Add(1)
+					}
+				}
+				producers.Done()
+			}()
+			go func() {
+				for {
+					if _, ok := r.take(); !ok {
+						break
+					}
+					delivered.Add(1)
+				}
+				consumers.Done()
+			}()
+		}
+		producers.Wait()
+		r.close()
+		consumers.Wait()
+
+		if n := delivered.Load() + dropped.Load(); n != raceCheckMessages {
+			return fmt.Errorf("%d events delivered and %d dropped, of %d offered", delivered.Load(), dropped.Load(), raceCheckMessages)
+		}
+		return nil
+	}
+}
+
+// chanLockCheck has several goroutines update a plain counter under a
+// chanLock, and checks that no update was lost.
+func chanLockCheck() error {
+	l := make(chanLock, 1)
+	counter := 0
+	var wg sync.WaitGroup
+	wg.Add(raceCheckGoroutines)
+	for g := 0; g < raceCheckGoroutines; g++ {
+		go func() {
+			for i := 0; i < raceCheckMessages/raceCheckGoroutines; i++ {
+				l.Lock()
+				counter++
+				l.Unlock()
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	if counter != raceCheckMessages {
+		return fmt.Errorf("counter reached %d after %d increments", counter, raceCheckMessages)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import "context"
+import "fmt"
+import "math"
+import "time"
+
+import "golang.org/x/time/rate"
+
+// Each rate limiter runs for rateLimitDuration at each target rate.
+const rateLimitDuration = 100 * time.Millisecond
+
+// refillTick is the slowest a token refilling goroutine is woken.
+const refillTick = time.Millisecond
+
+var targetRates = []float64{1000, 10000, 100000}
+
+// A limiter blocks until the next event is allowed; stop releases any
+// resources it holds.
+type limiter struct {
+	wait func()
+	stop func()
+}
+
+func rateLimitScenarios() []scenario {
+	limiters := []struct {
+		name string
+		make func(perSecond float64) limiter
+	}{
+		{"token bucket", tokenBucket},
+		{"time.Ticker", tickerLimiter},
+		{"rate.Limiter", xLimiter},
+	}
+	var list []scenario
+	for _, l := range limiters {
+		for _, r := range targetRates {
+			list = append(list, scenario{fmt.Sprintf("rate, %s, %s/s", l.name, formatValue(r)), rateLimited(r, l.make), tags("macro")})
+		}
+	}
+	return list
+}
+
+// tokenBucket is a buffered channel of tokens, refilled by a goroutine
+// with as many tokens as have accrued since its previous tick.
+func tokenBucket(perSecond float64) limiter {
+	tick := max(time.Duration(float64(time.Second)/perSecond), refillTick)
+	burst := max(1, int(math.Round(perSecond*tick.Seconds())))
+	tokens := make(chan struct{}, burst)
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		last := time.Now()
+		accrued := 0.0
+		for {
+			select {
+			case now := <-ticker.C:
+				accrued = refill(tokens, accrued+perSecond*now.Sub(last).Seconds())
+				last = now
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return limiter{wait: func() { <-tokens }, stop: func() { close(done) }}
+}
+
+// refill puts the whole tokens accrued into the bucket, and returns the
+// fraction left over for the next tick; once the bucket is full, the
+// surplus is lost.
+func refill(tokens chan<- struct{}, accrued float64) float64 {
+	for ; accrued >= 1; accrued-- {
+		select {
+		case tokens <- struct{}{}:
+		default:
+			return 0
+		}
+	}
+	return accrued
+}
+
+// tickerLimiter allows one event per tick; ticks are dropped when the
+// ticker is faster than the events can be taken.
+func tickerLimiter(perSecond float64) limiter {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / perSecond))
+	return limiter{wait: func() { <-ticker.C }, stop: ticker.Stop}
+}
+
+func xLimiter(perSecond float64) limiter {
+	l := rate.NewLimiter(rate.Limit(perSecond), 1)
+	ctx := context.Background()
+	return limiter{wait: func() { l.Wait(ctx) }, stop: func() {}}
+}
+
+// rateLimited takes as many events as the limiter allows during
+// rateLimitDuration, and reports how far the achieved rate is from the
+// target.
+func rateLimited(perSecond float64, newLimiter func(float64) limiter) func(iterations int) result {
+	return func(iterations int) result {
+		l := newLimiter(perSecond)
+
+		then := time.Now()
+		deadline := then.Add(rateLimitDuration)
+
+		n := 0
+		for ; time.Now().Before(deadline); n++ {
+			l.wait()
+		}
+
+		elapsed := time.Since(then)
+		l.stop()
+
+		achieved := float64(n) / elapsed.Seconds()
+		return result{
+			messages: n,
+			elapsed:  elapsed,
+			metrics: []metric{
+				{"rate error", 100 * (achieved - perSecond) / perSecond, "%"},
+			},
+		}
+	}
+}
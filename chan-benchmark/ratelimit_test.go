@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestRefill(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		queued   int
+		accrued  float64
+		tokens   int
+		left     float64
+	}{
+		{"fraction", 4, 0, 0.5, 0, 0.5},
+		{"whole and fraction", 4, 0, 2.25, 2, 0.25},
+		{"exactly full", 4, 0, 4, 4, 0},
+		{"overflow", 4, 0, 6.5, 4, 0},
+		{"already full", 4, 4, 3, 4, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := make(chan struct{}, tt.capacity)
+			for i := 0; i < tt.queued; i++ {
+				tokens <- struct{}{}
+			}
+			left := refill(tokens, tt.accrued)
+			if len(tokens) != tt.tokens || left != tt.left {
+				t.Errorf("refill(%v) = %d tokens, %v left; want %d tokens, %v left", tt.accrued, len(tokens), left, tt.tokens, tt.left)
+			}
+		})
+	}
+}
+
+func TestRefillOverflowKeepsNextTick(t *testing.T) {
+	tokens := make(chan struct{}, 2)
+	left := refill(tokens, 5)
+	<-tokens
+	<-tokens
+	// After an overflow, the next tick's single token must not be lost.
+	if refill(tokens, left+1); len(tokens) != 1 {
+		t.Errorf("%d tokens after the tick following an overflow, want 1", len(tokens))
+	}
+}
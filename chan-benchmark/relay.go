@@ -0,0 +1,70 @@
+package main
+
+import "time"
+
+// streamThrough streams iterations messages through relay, which reads
+// from in and writes to out until in is closed, then closes out.
+func streamThrough(iterations int, relay func(in <-chan int, out chan<- int)) result {
+	in := make(chan int)
+	out := make(chan int)
+	check := newSequenceCheck(1, iterations)
+	cold := newColdSplit(iterations)
+
+	then := time.Now()
+
+	go func() {
+		for i := 0; i < iterations; i++ {
+			in <- i
+		}
+		close(in)
+	}()
+	go relay(in, out)
+
+	var first time.Duration
+	for v := range out {
+		if first == 0 {
+			first = time.Since(then)
+		}
+		check.receive(0, v)
+		cold.receive(then)
+	}
+
+	return check.verified(cold.split(result{messages: iterations, elapsed: time.Since(then), firstMessage: first}))
+}
+
+// forwardingRelay receives, then sends, one message at a time.
+func forwardingRelay(iterations int) result {
+	return streamThrough(iterations, func(in <-chan int, out chan<- int) {
+		for v := range in {
+			out <- v
+		}
+		close(out)
+	})
+}
+
+// bufferingRelay is the classic relay loop: a single select both
+// receives new messages and sends queued ones, with the send case
+// disabled by a nil channel while the queue is empty.
+func bufferingRelay(iterations int) result {
+	return streamThrough(iterations, func(in <-chan int, out chan<- int) {
+		var queue []int
+		for in != nil || len(queue) > 0 {
+			var send chan<- int
+			var next int
+			if len(queue) > 0 {
+				send, next = out, queue[0]
+			}
+			select {
+			case v, ok := <-in:
+				if !ok {
+					in = nil
+					continue
+				}
+				queue = append(queue, v)
+			case send <- next:
+				queue = queue[1:]
+			}
+		}
+		close(out)
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import "flag"
+import "fmt"
+import "math"
+import "slices"
+import "time"
+
+var count = flag.Int("count", 1, "run each scenario `n` times and report the mean")
+
+var best = flag.Bool("best", false, "also report the best (minimum) time per message across repetitions")
+
+// repeat measures a scenario -count times and combines the runs. With a
+// non-negative allowance, it stops repeating once another run would not
+// fit in it, and notes the runs it trimmed.
+func repeat(s scenario, iterations int, allowance time.Duration) result {
+	wanted := max(1, *count)
+	runs := make([]result, 0, wanted)
+	then := time.Now()
+	for len(runs) < wanted {
+		r := measure(s, iterations)
+		writeMemProfile(s.name, len(runs)+1)
+		if r.err != nil {
+			return r
+		}
+		runs = append(runs, r)
+		perRun := time.Since(then) / time.Duration(len(runs))
+		if allowance >= 0 && time.Since(then)+perRun > allowance {
+			break
+		}
+	}
+	r := combine(runs)
+	if len(runs) < wanted {
+		r.notes = append(r.notes, fmt.Sprintf("-max-total trimmed the repetitions to %d of %d", len(runs), wanted))
+	}
+	return r
+}
+
+// combine averages repeated runs of one scenario into a single result,
+// which keeps the time per message of every run as its samples.
+func combine(runs []result) result {
+	r := runs[0]
+	r.repetitions = len(runs)
+	r.samples = nil
+	for _, run := range runs {
+		r.samples = append(r.samples, float64(run.elapsed)/float64(max(1, run.messages)))
+	}
+	if len(runs) == 1 {
+		return r
+	}
+
+	var elapsed, first time.Duration
+	for _, run := range runs {
+		elapsed += run.elapsed
+		first += run.firstMessage
+	}
+	r.elapsed = elapsed / time.Duration(len(runs))
+	r.firstMessage = first / time.Duration(len(runs))
+	r.metrics = averageMetrics(runs)
+
+	mean := float64(r.elapsed) / float64(r.messages)
+	var squares float64
+	for _, sample := range r.samples {
+		squares += (sample - mean) * (sample - mean)
+	}
+	r.metrics = append(r.metrics, metric{"stddev per message", math.Sqrt(squares / float64(len(runs)-1)), "ns"})
+	if *best {
+		r.metrics = append(r.metrics, metric{"best per message", slices.Min(r.samples), "ns"})
+	}
+	return r
+}
+
+// averageMetrics averages each metric over the runs that reported it,
+// in the order of first appearance.
+func averageMetrics(runs []result) []metric {
+	type key struct{ name, unit string }
+	var order []key
+	sums := make(map[key]float64)
+	counts := make(map[key]int)
+	for _, run := range runs {
+		for _, m := range run.metrics {
+			k := key{m.name, m.unit}
+			if counts[k] == 0 {
+				order = append(order, k)
+			}
+			sums[k] += m.value
+			counts[k]++
+		}
+	}
+	metrics := make([]metric, len(order))
+	for i, k := range order {
+		metrics[i] = metric{k.name, sums[k] / float64(counts[k]), k.unit}
+	}
+	return metrics
+}
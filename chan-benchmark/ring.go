@@ -0,0 +1,47 @@
+package main
+
+import "flag"
+import "time"
+
+var ringSize = flag.Int("ring", 503, "number of goroutines in the token-passing ring")
+
+// threadRing passes a token around a ring of goroutines, one hop per
+// iteration; every hop parks one goroutine and wakes the next. The stack
+// memory of the ring is measured before it is torn down.
+func threadRing(iterations int) result {
+	n := max(1, *ringSize)
+	links := make([]chan int, n)
+	for i := range links {
+		links[i] = make(chan int)
+	}
+	done := make(chan struct{})
+	stacks := stackInUse()
+
+	then := time.Now()
+
+	for i := 0; i < n; i++ {
+		go func(in, out chan int) {
+			for token := range in {
+				if token == 0 {
+					close(done)
+					continue
+				}
+				out <- token - 1
+			}
+		}(links[i], links[(i+1)%n])
+	}
+	links[0] <- iterations
+	<-done
+	elapsed := time.Since(then)
+	stack := stackGrowth(stacks, n)
+
+	for _, l := range links {
+		close(l)
+	}
+
+	return result{
+		messages: iterations,
+		elapsed:  elapsed,
+		metrics:  append([]metric{{"goroutines in ring", float64(n), ""}}, stack...),
+	}
+}
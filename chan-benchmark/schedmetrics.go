@@ -0,0 +1,69 @@
+package main
+
+import "math"
+import "runtime/metrics"
+
+const schedLatencies = "/sched/latencies:seconds"
+const mutexWait = "/sync/mutex/wait/total:seconds"
+
+// schedMetrics is a snapshot of the runtime's scheduler statistics.
+type schedMetrics struct {
+	latencies *metrics.Float64Histogram
+	mutexWait float64
+}
+
+func readSchedMetrics() schedMetrics {
+	samples := []metrics.Sample{{Name: schedLatencies}, {Name: mutexWait}}
+	metrics.Read(samples)
+
+	var m schedMetrics
+	if samples[0].Value.Kind() == metrics.KindFloat64Histogram {
+		m.latencies = samples[0].Value.Float64Histogram()
+	}
+	if samples[1].Value.Kind() == metrics.KindFloat64 {
+		m.mutexWait = samples[1].Value.Float64()
+	}
+	return m
+}
+
+// delta reports how long goroutines waited to be scheduled between the
+// two snapshots, as a count and as percentiles of the waits.
+func (m schedMetrics) delta(later schedMetrics) []metric {
+	list := []metric{{"runtime mutex wait", (later.mutexWait - m.mutexWait) * 1e9, "ns"}}
+	if m.latencies == nil || later.latencies == nil {
+		return list
+	}
+
+	counts := make([]uint64, len(later.latencies.Counts))
+	var total uint64
+	for i := range counts {
+		counts[i] = later.latencies.Counts[i] - m.latencies.Counts[i]
+		total += counts[i]
+	}
+	list = append(list, metric{"scheduling events", float64(total), ""})
+	for _, p := range []float64{50, 99} {
+		v := histogramPercentile(later.latencies.Buckets, counts, total, p)
+		list = append(list, metric{"p" + formatValue(p) + " sched latency", v * 1e9, "ns"})
+	}
+	return list
+}
+
+// histogramPercentile returns the upper boundary of the bucket holding
+// the p-th percentile, or its lower boundary for the unbounded bucket.
+func histogramPercentile(buckets []float64, counts []uint64, total uint64, p float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(p / 100 * float64(total)))
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen >= target {
+			if math.IsInf(buckets[i+1], 1) {
+				return buckets[i]
+			}
+			return buckets[i+1]
+		}
+	}
+	return buckets[len(buckets)-1]
+}
@@ -0,0 +1,127 @@
+package main
+
+// schemaVersion is the version of the JSON output, recorded in every
+// document as "schema". It changes whenever a field is removed or its
+// meaning changes; adding fields does not change it. Documents without
+// it predate versioning.
+const schemaVersion = 1
+
+// jsonSchema describes the JSON output, as printed by the schema
+// subcommand.
+const jsonSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "chan-benchmark results",
+  "type": "object",
+  "required": ["schema", "environment", "seed", "results"],
+  "properties": {
+    "schema": {"const": 1, "description": "version of this schema"},
+    "partial": {"type": "boolean", "description": "the run was interrupted"},
+    "seed": {"type": "integer", "description": "the -seed of every random choice"},
+    "environment": {
+      "type": "object",
+      "properties": {
+        "started": {"type": "string", "format": "date-time"},
+        "go_version": {"type": "string"},
+        "goos": {"type": "string"},
+        "goarch": {"type": "string"},
+        "num_cpu": {"type": "integer"},
+        "gomaxprocs": {"type": "integer"},
+        "hostname": {"type": "string"}
+      }
+    },
+    "results": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name", "messages", "elapsed_ns", "ns_per_message", "estimator", "repetitions"],
+        "properties": {
+          "name": {"type": "string", "description": "scenario name, unique in a document"},
+          "messages": {"type": "integer"},
+          "elapsed_ns": {"type": "integer"},
+          "ns_per_message": {"type": "number"},
+          "first_message_ns": {"type": "integer"},
+          "estimator": {"enum": ["single run", "mean"]},
+          "repetitions": {"type": "integer"},
+          "samples_ns_per_message": {"type": "array", "items": {"type": "number"}},
+          "metrics": {"type": "array", "items": {"$ref": "#/$defs/metric"}},
+          "notes": {"type": "array", "items": {"type": "string"}},
+          "participants": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "role": {"type": "string"},
+                "index": {"type": "integer"},
+                "messages": {"type": "integer"},
+                "blocked_ns": {"type": "integer"},
+                "elapsed_ns": {"type": "integer"},
+                "messages_per_second": {"type": "number"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "footprints": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string"},
+          "channels": {"type": "integer"},
+          "bytes_per_channel": {"type": "number"}
+        }
+      }
+    },
+    "load_curve": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "offered_per_second": {"type": "number"},
+          "achieved_per_second": {"type": "number"},
+          "saturated": {"type": "boolean"},
+          "latency": {"type": "array", "items": {"$ref": "#/$defs/metric"}}
+        }
+      }
+    },
+    "recommendations": {
+      "type": "object",
+      "description": "keyed by the sweep each recommendation comes from",
+      "additionalProperties": {
+        "type": "object",
+        "properties": {
+          "capacity": {"type": "integer"},
+          "messages_per_second": {"type": "number"},
+          "peak_messages_per_second": {"type": "number"},
+          "within_percent": {"type": "number"}
+        }
+      }
+    },
+    "skipped": {"type": "array", "items": {"type": "string"}, "description": "scenarios -max-total left no time for"},
+    "failed": {
+      "type": "array",
+      "description": "scenarios that could not run",
+      "items": {
+        "type": "object",
+        "required": ["scenario", "error"],
+        "properties": {
+          "scenario": {"type": "string"},
+          "error": {"type": "string"}
+        }
+      }
+    }
+  },
+  "$defs": {
+    "metric": {
+      "type": "object",
+      "required": ["name", "value", "unit"],
+      "properties": {
+        "name": {"type": "string"},
+        "value": {"type": "number"},
+        "unit": {"type": "string", "description": "such as ns, B, B/op, allocs/op, %, x or MB/s; empty for a count"}
+      }
+    }
+  }
+}
+`
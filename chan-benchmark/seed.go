@@ -0,0 +1,43 @@
+package main
+
+import "flag"
+import "math/rand"
+import "strings"
+
+var seed = flag.Int64("seed", 1, "`seed` of every random choice, such as job costs, payload contents and the -shuffle order; it is echoed in the output")
+
+var shuffle = flag.Bool("shuffle", false, "run the scenarios in an order drawn from -seed, keeping each family of scenarios, such as a sweep, together and in order")
+
+// newRand returns a random source starting from -seed, so that every
+// scenario drawing from one sees the same sequence in every run.
+func newRand() *rand.Rand {
+	return rand.New(rand.NewSource(*seed))
+}
+
+// family is the first word of the name of a scenario, which it shares
+// with the rest of its sweep or of its comparison.
+func family(name string) string {
+	word, _, _ := strings.Cut(name, " ")
+	return strings.TrimSuffix(word, ",")
+}
+
+// shuffled returns the scenarios with their consecutive families in an
+// order drawn from -seed, each family keeping its own order, so that a
+// sweep still starts from its single goroutine case.
+func shuffled(scenarios []scenario) []scenario {
+	var families [][]scenario
+	for i, s := range scenarios {
+		if i == 0 || family(s.name) != family(scenarios[i-1].name) {
+			families = append(families, nil)
+		}
+		families[len(families)-1] = append(families[len(families)-1], s)
+	}
+	newRand().Shuffle(len(families), func(i, j int) {
+		families[i], families[j] = families[j], families[i]
+	})
+	list := make([]scenario, 0, len(scenarios))
+	for _, f := range families {
+		list = append(list, f...)
+	}
+	return list
+}
@@ -0,0 +1,149 @@
+package main
+
+import "sync"
+import "sync/atomic"
+import "time"
+
+// semaphore is a counting semaphore whose waiters park in the runtime,
+// standing in for the SChanSemaphore of the Swift channels.
+type semaphore struct {
+	mu    sync.Mutex
+	cond  sync.Cond
+	count int
+}
+
+func newSemaphore(value int) *semaphore {
+	s := &semaphore{count: value}
+	s.cond.L = &s.mu
+	return s
+}
+
+func (s *semaphore) wait() {
+	s.mu.Lock()
+	for s.count == 0 {
+		s.cond.Wait()
+	}
+	s.count--
+	s.mu.Unlock()
+}
+
+func (s *semaphore) signal() {
+	s.mu.Lock()
+	s.count++
+	s.mu.Unlock()
+	s.cond.Signal()
+}
+
+// semChan is a one-slot rendezvous built the way SBufferedChan is: a
+// buffer guarded by a semaphore counting filled slots and another
+// counting empty ones.
+type semChan struct {
+	element int
+	head    atomic.Int64
+	tail    atomic.Int64
+	closed  atomic.Bool
+	filled  *semaphore
+	empty   *semaphore
+}
+
+func newSemChan() *semChan {
+	return &semChan{filled: newSemaphore(0), empty: newSemaphore(1)}
+}
+
+func (c *semChan) close() {
+	if c.closed.CompareAndSwap(false, true) {
+		c.filled.signal()
+		c.empty.signal()
+	}
+}
+
+func (c *semChan) put(v int) bool {
+	if c.closed.Load() {
+		return false
+	}
+	c.empty.wait()
+	if c.closed.Load() {
+		c.empty.signal()
+		return false
+	}
+	c.tail.Add(1)
+	c.element = v
+	c.filled.signal()
+	return true
+}
+
+func (c *semChan) get() (int, bool) {
+	if c.closed.Load() && c.tail.Load()-c.head.Load() <= 0 {
+		return 0, false
+	}
+	c.filled.wait()
+	head := c.head.Add(1)
+	if c.tail.Load()-head < 0 {
+		// closed while empty: let the next receiver through too
+		c.head.Add(-1)
+		c.filled.signal()
+		return 0, false
+	}
+	v := c.element
+	c.empty.signal()
+	return v, true
+}
+
+// semStream is the streaming handoff scenario over a semChan.
+func semStream(iterations int) result {
+	c := newSemChan()
+	check := newSequenceCheck(1, iterations)
+	cold := newColdSplit(iterations)
+
+	then := time.Now()
+
+	go func() {
+		for i := 0; i < iterations; i++ {
+			c.put(i)
+		}
+		c.close()
+	}()
+
+	var first time.Duration
+	for {
+		v, ok := c.get()
+		if !ok {
+			break
+		}
+		if first == 0 {
+			first = time.Since(then)
+		}
+		check.receive(0, v)
+		cold.receive(then)
+	}
+
+	return check.verified(cold.split(result{messages: iterations, elapsed: time.Since(then), firstMessage: first}))
+}
+
+// semPingPong is the ping-pong scenario over a pair of semChans.
+func semPingPong(iterations int) result {
+	ping, pong := newSemChan(), newSemChan()
+	check := newSequenceCheck(1, iterations)
+	go func() {
+		for {
+			v, ok := ping.get()
+			if !ok {
+				break
+			}
+			pong.put(v)
+		}
+		pong.close()
+	}()
+
+	then := time.Now()
+
+	for i := 0; i < iterations; i++ {
+		ping.put(i)
+		v, _ := pong.get()
+		check.receive(0, v)
+	}
+	elapsed := time.Since(then)
+
+	ping.close()
+	return check.verified(result{messages: iterations, elapsed: elapsed})
+}
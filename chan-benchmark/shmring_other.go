@@ -0,0 +1,19 @@
+//go:build !unix
+
+package main
+
+import "errors"
+
+const shmRingEnv = "CHAN_BENCHMARK_SHM_RING"
+
+func shmRingChild(spec string) {}
+
+func removeRingFiles() {}
+
+func shmRingCheck(messages int) error {
+	return errUnsupported
+}
+
+func shmRingStream(iterations int) result {
+	return result{err: errors.New("shared memory rings are only supported on unix systems")}
+}
@@ -0,0 +1,254 @@
+//go:build unix
+
+package main
+
+import "errors"
+import "fmt"
+import "os"
+import "os/exec"
+import "strconv"
+import "strings"
+import "sync"
+import "sync/atomic"
+import "syscall"
+import "time"
+import "unsafe"
+
+// shmRingEnv is set in the environment of the child process that
+// consumes from the shared-memory ring, to the file and message count,
+// followed by ":verify" under -verify.
+const shmRingEnv = "CHAN_BENCHMARK_SHM_RING"
+
+// Layout of the shared ring: each index on its own cache line, then the
+// slots. The producer owns tail, the consumer owns head, ready and done,
+// and the outcome of -verify next to done: the number of messages out of
+// sequence, and the first one received with the one expected in its
+// place.
+const (
+	shmHead    = 0
+	shmTail    = cacheLine
+	shmReady   = 2 * cacheLine
+	shmDone    = 3 * cacheLine
+	shmErrors  = shmDone + 8
+	shmBadSeq  = shmDone + 16
+	shmBadWant = shmDone + 24
+	shmSlots   = 4 * cacheLine
+	shmLength  = 4096
+	shmSize    = shmSlots + 8*shmLength
+)
+
+// errShmChildExited is returned when the consumer process exits before
+// taking every message, so that its producer does not wait forever.
+var errShmChildExited = errors.New("the consumer process exited early")
+
+// shmOrphanCheck is how many spins the consumer process waits between
+// checks that its parent is still alive.
+const shmOrphanCheck = 1 << 10
+
+// ringFiles holds the ring files in use, for removeRingFiles.
+var ringFiles sync.Map
+
+// removeRingFiles removes the ring files in use, which an interrupted
+// benchmark leaves behind because os.Exit skips their deferred removal.
+func removeRingFiles() {
+	ringFiles.Range(func(path, _ any) bool {
+		os.Remove(path.(string))
+		return true
+	})
+}
+
+// shmRing is a single-producer single-consumer ring in a shared mapping.
+// When exited is set, waiting on the other side gives up once it is true;
+// when parent is set, the consumer exits once its parent process is gone.
+type shmRing struct {
+	mem    []byte
+	exited *atomic.Bool
+	parent int
+}
+
+// wait spins, then sleeps, until cond holds, or until the other process
+// has exited.
+func (r shmRing) wait(cond func() bool) error {
+	for spins := 0; !cond(); spins++ {
+		if r.exited != nil && r.exited.Load() {
+			return errShmChildExited
+		}
+		backoff(spins)
+	}
+	return nil
+}
+
+func (r shmRing) word(offset int) *atomic.Uint64 {
+	return (*atomic.Uint64)(unsafe.Pointer(&r.mem[offset]))
+}
+
+func (r shmRing) slot(i uint64) *uint64 {
+	return (*uint64)(unsafe.Pointer(&r.mem[shmSlots+8*int(i%shmLength)]))
+}
+
+// put waits for room in the ring, then stores v as message t.
+func (r shmRing) put(t, v uint64) error {
+	head := r.word(shmHead)
+	if t-head.Load() == shmLength {
+		if err := r.wait(func() bool { return t-head.Load() < shmLength }); err != nil {
+			return err
+		}
+	}
+	*r.slot(t) = v
+	r.word(shmTail).Store(t + 1)
+	return nil
+}
+
+// take waits for message h, then frees its slot.
+func (r shmRing) take(h uint64) uint64 {
+	tail := r.word(shmTail)
+	for spins := 0; tail.Load() == h; spins++ {
+		if r.parent != 0 && spins%shmOrphanCheck == shmOrphanCheck-1 && os.Getppid() != r.parent {
+			os.Exit(1)
+		}
+		backoff(spins)
+	}
+	v := *r.slot(h)
+	r.word(shmHead).Store(h + 1)
+	return v
+}
+
+// backoff spins for a while, then sleeps so that the other process can
+// run even when both share a CPU.
+func backoff(n int) {
+	if n > 100 {
+		time.Sleep(time.Microsecond)
+	}
+}
+
+func mapShmRing(path string) (shmRing, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return shmRing{}, err
+	}
+	defer f.Close()
+	mem, err := syscall.Mmap(int(f.Fd()), 0, shmSize, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return shmRing{}, os.NewSyscallError("mmap", err)
+	}
+	return shmRing{mem: mem}, nil
+}
+
+// shmRingChild is the consumer side, run in a child process.
+func shmRingChild(spec string) {
+	path, rest, _ := strings.Cut(spec, ":")
+	n, verifying, _ := strings.Cut(rest, ":")
+	messages, _ := strconv.ParseUint(n, 10, 64)
+	r, err := mapShmRing(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	r.parent = os.Getppid()
+
+	r.word(shmReady).Store(1)
+	var sum, errors uint64
+	for h := uint64(0); h < messages; h++ {
+		v := r.take(h)
+		sum += v
+		if verifying != "" && v != h {
+			if errors == 0 {
+				r.word(shmBadSeq).Store(v)
+				r.word(shmBadWant).Store(h)
+			}
+			errors++
+		}
+	}
+	r.word(shmErrors).Store(errors)
+	r.word(shmDone).Store(1)
+}
+
+// shmRingCheck runs the ring between two goroutines of this process, on
+// ordinary memory, and checks that every message arrives in order.
+func shmRingCheck(messages int) error {
+	r := shmRing{mem: make([]byte, shmSize)}
+	go func() {
+		for t := uint64(0); t < uint64(messages); t++ {
+			r.put(t, t)
+		}
+	}()
+	for h := uint64(0); h < uint64(messages); h++ {
+		if v := r.take(h); v != h {
+			return fmt.Errorf("received message %d in place of %d", v, h)
+		}
+	}
+	return nil
+}
+
+// shmRingStream streams iterations messages to a child process through
+// a shared-memory ring, as a cross-process reference for the channels.
+func shmRingStream(iterations int) result {
+	f, err := os.CreateTemp("", "chan-benchmark-ring")
+	if err != nil {
+		return result{err: err}
+	}
+	ringFiles.Store(f.Name(), true)
+	defer ringFiles.Delete(f.Name())
+	defer os.Remove(f.Name())
+	err = f.Truncate(shmSize)
+	f.Close()
+	if err != nil {
+		return result{err: err}
+	}
+	r, err := mapShmRing(f.Name())
+	if err != nil {
+		return result{err: err}
+	}
+	defer syscall.Munmap(r.mem)
+
+	self, err := os.Executable()
+	if err != nil {
+		return result{err: err}
+	}
+	check := newSequenceCheck(1, iterations)
+	spec := fmt.Sprintf("%s:%d", f.Name(), iterations)
+	if check != nil {
+		spec += ":verify"
+	}
+	child := exec.Command(self)
+	child.Env = append(os.Environ(), shmRingEnv+"="+spec)
+	child.Stderr = os.Stderr
+	if err := child.Start(); err != nil {
+		return result{err: err}
+	}
+	r.exited = new(atomic.Bool)
+	waited := make(chan error, 1)
+	go func() {
+		err := child.Wait()
+		r.exited.Store(true)
+		waited <- err
+	}()
+	fail := func(err error) result {
+		if waitErr := <-waited; waitErr != nil {
+			err = fmt.Errorf("%w: %v", err, waitErr)
+		}
+		return result{err: err}
+	}
+
+	if err := r.wait(func() bool { return r.word(shmReady).Load() != 0 }); err != nil {
+		return fail(err)
+	}
+
+	then := time.Now()
+
+	for t := uint64(0); t < uint64(iterations); t++ {
+		if err := r.put(t, t); err != nil {
+			return fail(err)
+		}
+	}
+	if err := r.wait(func() bool { return r.word(shmDone).Load() != 0 }); err != nil {
+		return fail(err)
+	}
+	elapsed := time.Since(then)
+
+	if err := <-waited; err != nil {
+		return result{err: fmt.Errorf("consumer process: %w", err)}
+	}
+	check.remote(0, iterations, int(r.word(shmErrors).Load()), int(r.word(shmBadSeq).Load()), int(r.word(shmBadWant).Load()))
+	return check.verified(result{messages: iterations, elapsed: elapsed})
+}
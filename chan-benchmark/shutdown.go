@@ -0,0 +1,99 @@
+package main
+
+import "time"
+
+const shutdownCapacity = 1024
+
+// shutdownResult reports a stop after the consumer handled iterations
+// messages: how long until it exited, and what happened to the messages
+// still queued at the time.
+func shutdownResult(iterations int, elapsed, latency time.Duration, after, abandoned int) result {
+	return result{
+		messages: iterations + after,
+		elapsed:  elapsed,
+		metrics: []metric{
+			{"shutdown latency", float64(latency), "ns"},
+			{"processed after stop", float64(after), ""},
+			{"abandoned", float64(abandoned), ""},
+		},
+	}
+}
+
+// closeAndDrain stops the producer, which closes the channel; the
+// consumer handles every message still buffered before exiting.
+func closeAndDrain(iterations int) result {
+	ch := make(chan int, shutdownCapacity)
+	stop := make(chan struct{})
+	go func() {
+		defer close(ch)
+		for i := 0; ; i++ {
+			select {
+			case ch <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	then := time.Now()
+
+	busy := 0
+	for i := 0; i < iterations; i++ {
+		<-ch
+		busy += work(consumerWork)
+	}
+	stopped := time.Now()
+	close(stop)
+
+	after := 0
+	for range ch {
+		busy += work(consumerWork)
+		after++
+	}
+	done := time.Now()
+	sink(busy)
+
+	return shutdownResult(iterations, done.Sub(then), done.Sub(stopped), after, 0)
+}
+
+// doneAndAbandon signals both sides on a done channel; the consumer
+// exits as soon as it notices, abandoning whatever is still buffered.
+func doneAndAbandon(iterations int) result {
+	ch := make(chan int, shutdownCapacity)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case ch <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	then := time.Now()
+
+	busy := 0
+	for i := 0; i < iterations; i++ {
+		<-ch
+		busy += work(consumerWork)
+	}
+	stopped := time.Now()
+	close(done)
+
+	after := 0
+consume:
+	for {
+		select {
+		case <-ch:
+			busy += work(consumerWork)
+			after++
+		case <-done:
+			break consume
+		}
+	}
+	exited := time.Now()
+	sink(busy)
+
+	return shutdownResult(iterations, exited.Sub(then), exited.Sub(stopped), after, len(ch))
+}
@@ -0,0 +1,60 @@
+package main
+
+import "flag"
+import "time"
+
+var sievePrimes = flag.Int("primes", 1000, "number of primes found by the concurrent prime sieve")
+
+func sieveFilter(in <-chan int, out chan<- int, prime int) {
+	for i := range in {
+		if i%prime != 0 {
+			out <- i
+		}
+	}
+	close(out)
+}
+
+// primeSieve is the channel-chained sieve: every prime found adds a
+// filter goroutine to the end of the pipeline. It runs until -primes
+// primes are found, and reports the time per prime and the stack memory
+// of its goroutines.
+func primeSieve(iterations int) result {
+	n := max(1, *sievePrimes)
+	stop := make(chan struct{})
+	stacks := stackInUse()
+
+	then := time.Now()
+
+	source := make(chan int)
+	go func() {
+		defer close(source)
+		for i := 2; ; i++ {
+			select {
+			case source <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	prime := 0
+	candidates := (<-chan int)(source)
+	for found := 0; found < n; found++ {
+		prime = <-candidates
+		out := make(chan int)
+		go sieveFilter(candidates, out, prime)
+		candidates = out
+	}
+	elapsed := time.Since(then)
+	stack := stackGrowth(stacks, n+1)
+
+	close(stop)
+	for range candidates {
+	}
+
+	return result{
+		messages: n,
+		elapsed:  elapsed,
+		metrics:  append([]metric{{"last prime", float64(prime), ""}}, stack...),
+	}
+}
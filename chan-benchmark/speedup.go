@@ -0,0 +1,29 @@
+package main
+
+import "time"
+
+// speedups remembers the per-message time of each sweep's single
+// goroutine case.
+type speedups map[string]time.Duration
+
+// annotate adds speedup and parallel efficiency to a result belonging to
+// a sweep whose single goroutine case has already been measured.
+func (s speedups) annotate(r *result) {
+	if r.sweep == "" || r.parallelism == 0 || r.messages == 0 {
+		return
+	}
+	perMessage := r.elapsed / time.Duration(r.messages)
+	if r.parallelism == 1 {
+		s[r.sweep] = perMessage
+		return
+	}
+	base, ok := s[r.sweep]
+	if !ok || perMessage == 0 {
+		return
+	}
+	speedup := float64(base) / float64(perMessage)
+	r.metrics = append(r.metrics,
+		metric{"speedup", speedup, "x"},
+		metric{"parallel efficiency", 100 * speedup / float64(r.parallelism), "%"},
+	)
+}
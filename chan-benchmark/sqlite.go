@@ -0,0 +1,223 @@
+package main
+
+import "flag"
+import "fmt"
+import "os"
+import "os/exec"
+import "strconv"
+import "strings"
+import "time"
+
+// The result store is written through the sqlite3 command-line shell,
+// which keeps this program free of cgo and third-party drivers.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY,
+	started TEXT, go_version TEXT, goos TEXT, goarch TEXT,
+	num_cpu INTEGER, gomaxprocs INTEGER, hostname TEXT,
+	partial INTEGER
+);
+CREATE TABLE IF NOT EXISTS parameters (
+	run_id INTEGER REFERENCES runs(id),
+	name TEXT, value TEXT
+);
+CREATE TABLE IF NOT EXISTS results (
+	run_id INTEGER REFERENCES runs(id),
+	scenario TEXT, messages INTEGER, elapsed_ns INTEGER,
+	ns_per_message REAL, first_message_ns INTEGER
+);
+CREATE TABLE IF NOT EXISTS metrics (
+	run_id INTEGER REFERENCES runs(id),
+	scenario TEXT, name TEXT, value REAL, unit TEXT
+);
+CREATE TABLE IF NOT EXISTS samples (
+	run_id INTEGER REFERENCES runs(id),
+	scenario TEXT, repetition INTEGER, ns_per_message REAL
+);
+CREATE TABLE IF NOT EXISTS notes (
+	run_id INTEGER REFERENCES runs(id),
+	scenario TEXT, note TEXT
+);
+CREATE TABLE IF NOT EXISTS participants (
+	run_id INTEGER REFERENCES runs(id),
+	scenario TEXT, role TEXT, idx INTEGER, messages INTEGER,
+	blocked_ns INTEGER, elapsed_ns INTEGER, messages_per_second REAL
+);
+CREATE TABLE IF NOT EXISTS footprints (
+	run_id INTEGER REFERENCES runs(id),
+	channel TEXT, channels INTEGER, bytes_per_channel REAL
+);
+CREATE TABLE IF NOT EXISTS recommendations (
+	run_id INTEGER REFERENCES runs(id),
+	sweep TEXT, capacity INTEGER, messages_per_second REAL,
+	peak_messages_per_second REAL, within_percent REAL
+);
+CREATE TABLE IF NOT EXISTS load_curve (
+	run_id INTEGER REFERENCES runs(id),
+	offered_per_second REAL, achieved_per_second REAL,
+	name TEXT, value REAL, unit TEXT
+);
+`
+
+// sqliteRunID is the id of the run being written, which finish keeps in
+// a temporary table right after inserting it: max(id) could be another
+// run's, written at the same time.
+const sqliteRunID = "(SELECT id FROM current_run)"
+
+// sqliteOutput accumulates one run's statements and hands them to the
+// sqlite3 shell in a single transaction when the run finishes.
+type sqliteOutput struct {
+	path string
+	env  environment
+	sql  strings.Builder
+}
+
+// newSQLiteOutput fails at once if there is no sqlite3 shell, rather than
+// when the results are ready.
+func newSQLiteOutput(path string, env environment) (*sqliteOutput, error) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return nil, fmt.Errorf("-db needs the sqlite3 shell: %w", err)
+	}
+	return &sqliteOutput{path: path, env: env}, nil
+}
+
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlReal formats v as a REAL literal, or NULL when it is not finite.
+func sqlReal(v float64) string {
+	if finite(v) != v {
+		return "NULL"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func (d *sqliteOutput) result(name string, r result) {
+	fmt.Fprintf(&d.sql, "INSERT INTO results VALUES (%s, %s, %d, %d, %s, %d);\n",
+		sqliteRunID, sqlQuote(name), r.messages, r.elapsed.Nanoseconds(),
+		sqlReal(perMessage(r)), r.firstMessage.Nanoseconds())
+	for _, m := range r.metrics {
+		fmt.Fprintf(&d.sql, "INSERT INTO metrics VALUES (%s, %s, %s, %s, %s);\n",
+			sqliteRunID, sqlQuote(name), sqlQuote(m.name), sqlReal(m.value), sqlQuote(m.unit))
+	}
+	for i, sample := range r.samples {
+		fmt.Fprintf(&d.sql, "INSERT INTO samples VALUES (%s, %s, %d, %s);\n", sqliteRunID, sqlQuote(name), i+1, sqlReal(sample))
+	}
+	for _, p := range r.participants {
+		fmt.Fprintf(&d.sql, "INSERT INTO participants VALUES (%s, %s, %s, %d, %d, %d, %d, %s);\n",
+			sqliteRunID, sqlQuote(name), sqlQuote(p.role), p.index, p.messages,
+			p.blocked.Nanoseconds(), p.elapsed.Nanoseconds(), sqlReal(p.throughput()))
+	}
+	for _, n := range r.notes {
+		fmt.Fprintf(&d.sql, "INSERT INTO notes VALUES (%s, %s, %s);\n", sqliteRunID, sqlQuote(name), sqlQuote(n))
+	}
+}
+
+func (d *sqliteOutput) footprints(list []footprint) {
+	for _, f := range list {
+		fmt.Fprintf(&d.sql, "INSERT INTO footprints VALUES (%s, %s, %d, %s);\n",
+			sqliteRunID, sqlQuote(f.name), f.channels, sqlReal(f.bytes))
+	}
+}
+
+func (d *sqliteOutput) loadCurve(curve []loadPoint) {
+	for _, p := range curve {
+		for _, m := range p.latency {
+			fmt.Fprintf(&d.sql, "INSERT INTO load_curve VALUES (%s, %s, %s, %s, %s, %s);\n",
+				sqliteRunID, sqlReal(p.offered), sqlReal(p.achieved), sqlQuote(m.name), sqlReal(m.value), sqlQuote(m.unit))
+		}
+	}
+}
+
+func (d *sqliteOutput) recommendation(rec recommendation) {
+	fmt.Fprintf(&d.sql, "INSERT INTO recommendations VALUES (%s, %s, %d, %s, %s, %s);\n",
+		sqliteRunID, sqlQuote(rec.sweep), rec.capacity, sqlReal(rec.throughput), sqlReal(rec.peak), sqlReal(rec.within))
+}
+
+func (d *sqliteOutput) skipped(names []string) {
+	for _, n := range names {
+		fmt.Fprintf(&d.sql, "INSERT INTO notes VALUES (%s, %s, %s);\n", sqliteRunID, sqlQuote(n), sqlQuote("skipped: -max-total left no time for it"))
+	}
+}
+
+func (d *sqliteOutput) failed(name string, err error) {
+	fmt.Fprintf(&d.sql, "INSERT INTO notes VALUES (%s, %s, %s);\n", sqliteRunID, sqlQuote(name), sqlQuote("failed: "+err.Error()))
+}
+
+func (d *sqliteOutput) finish(partial bool) {
+	var script strings.Builder
+	script.WriteString(sqliteSchema)
+	// An immediate transaction takes the write lock before the run is
+	// inserted; the shell's busy timeout makes concurrent runs wait for
+	// it rather than fail.
+	script.WriteString("BEGIN IMMEDIATE;\n")
+
+	partialFlag := 0
+	if partial {
+		partialFlag = 1
+	}
+	fmt.Fprintf(&script, "INSERT INTO runs VALUES (NULL, %s, %s, %s, %s, %d, %d, %s, %d);\n",
+		sqlQuote(d.env.Started.Format(time.RFC3339)), sqlQuote(d.env.GoVersion),
+		sqlQuote(d.env.GOOS), sqlQuote(d.env.GOARCH), d.env.NumCPU, d.env.GOMAXPROCS,
+		sqlQuote(d.env.Hostname), partialFlag)
+	script.WriteString("CREATE TEMP TABLE current_run AS SELECT last_insert_rowid() AS id;\n")
+	flag.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(&script, "INSERT INTO parameters VALUES (%s, %s, %s);\n",
+			sqliteRunID, sqlQuote(f.Name), sqlQuote(f.Value.String()))
+	})
+	script.WriteString(d.sql.String())
+	script.WriteString("COMMIT;\n")
+
+	cmd := exec.Command("sqlite3", "-bail", "-cmd", ".timeout 60000", d.path)
+	cmd.Stdin = strings.NewReader(script.String())
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "writing results to %s: %v\n", d.path, err)
+	}
+}
+
+// multiOutput sends results to several outputs.
+type multiOutput []output
+
+func (m multiOutput) result(name string, r result) {
+	for _, o := range m {
+		o.result(name, r)
+	}
+}
+
+func (m multiOutput) footprints(list []footprint) {
+	for _, o := range m {
+		o.footprints(list)
+	}
+}
+
+func (m multiOutput) loadCurve(curve []loadPoint) {
+	for _, o := range m {
+		o.loadCurve(curve)
+	}
+}
+
+func (m multiOutput) recommendation(rec recommendation) {
+	for _, o := range m {
+		o.recommendation(rec)
+	}
+}
+
+func (m multiOutput) skipped(names []string) {
+	for _, o := range m {
+		o.skipped(names)
+	}
+}
+
+func (m multiOutput) failed(name string, err error) {
+	for _, o := range m {
+		o.failed(name, err)
+	}
+}
+
+func (m multiOutput) finish(partial bool) {
+	for _, o := range m {
+		o.finish(partial)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import "runtime/metrics"
+
+const stackBytes = "/memory/classes/heap/stacks:bytes"
+
+// stackInUse returns the memory currently used for goroutine stacks.
+func stackInUse() uint64 {
+	samples := []metrics.Sample{{Name: stackBytes}}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return samples[0].Value.Uint64()
+}
+
+// stackGrowth reports the stack memory used per goroutine started since
+// the before snapshot, while those goroutines are still running. New
+// goroutines can reuse the stacks of exited ones, which were already in
+// use at the snapshot, so this is a lower bound.
+func stackGrowth(before uint64, goroutines int) []metric {
+	after := stackInUse()
+	return []metric{
+		{"stack memory", float64(int64(after) - int64(before)), "B"},
+		{"stack per goroutine", float64(int64(after)-int64(before)) / float64(max(1, goroutines)), "B"},
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import "runtime"
+import "slices"
+import "time"
+
+// percentile returns the p-th percentile (0 to 100) of sorted samples,
+// using the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted)) + 0.5)
+	return sorted[min(max(rank-1, 0), len(sorted)-1)]
+}
+
+// distribution summarizes samples as metrics named after the quantity
+// they describe, e.g. "p99 send stall".
+func distribution(quantity string, samples []time.Duration) []metric {
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+
+	var metrics []metric
+	for _, p := range []float64{50, 90, 99, 99.9} {
+		name := "p" + formatValue(p) + " " + quantity
+		metrics = append(metrics, metric{name, float64(percentile(sorted, p)), "ns"})
+	}
+	return append(metrics, metric{"max " + quantity, float64(percentile(sorted, 100)), "ns"})
+}
+
+// allocations reports the heap allocations made per operation between
+// two memory statistics snapshots.
+func allocations(before, after *runtime.MemStats, n int) []metric {
+	return []metric{
+		{"allocations", float64(after.Mallocs-before.Mallocs) / float64(n), "allocs/op"},
+		{"allocated", float64(after.TotalAlloc-before.TotalAlloc) / float64(n), "B/op"},
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import "runtime"
+import "testing"
+import "time"
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, 10},
+		{10, 10},
+		{50, 50},
+		{90, 90},
+		{99, 100},
+		{100, 100},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile of no samples = %v, want 0", got)
+	}
+	if got := percentile([]time.Duration{7}, 99.9); got != 7 {
+		t.Errorf("percentile of one sample = %v, want 7", got)
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	samples := []time.Duration{5, 1, 4, 2, 3}
+	got := distribution("stall", samples)
+	want := []metric{
+		{"p50 stall", 3, "ns"},
+		{"p90 stall", 5, "ns"},
+		{"p99 stall", 5, "ns"},
+		{"p99.9 stall", 5, "ns"},
+		{"max stall", 5, "ns"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("distribution = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("distribution[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if samples[0] != 5 {
+		t.Error("distribution sorted the samples in place")
+	}
+}
+
+func TestAllocations(t *testing.T) {
+	before := &runtime.MemStats{Mallocs: 100, TotalAlloc: 1000}
+	after := &runtime.MemStats{Mallocs: 110, TotalAlloc: 1800}
+	got := allocations(before, after, 10)
+	want := []metric{{"allocations", 1, "allocs/op"}, {"allocated", 80, "B/op"}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("allocations[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
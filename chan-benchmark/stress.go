@@ -0,0 +1,100 @@
+package main
+
+import "context"
+import "fmt"
+import "os"
+import "os/signal"
+import "runtime"
+import "slices"
+import "time"
+
+// stressBatch is the number of iterations given to each run of the
+// stressed scenario, which measure its throughput. Each run is followed
+// by a probe, a run of a single message, whose time is one sample of
+// the latency per message: a batch mean would hide the tail.
+const stressBatch = 1000
+
+func findScenario(name string) (scenario, bool) {
+	for _, s := range scenarios {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return scenario{}, false
+}
+
+// probed formats the p-th percentile of the sorted probe samples, or
+// n/a if there are none.
+func probed(sorted []time.Duration, p float64) string {
+	if len(sorted) == 0 {
+		return "n/a"
+	}
+	return percentile(sorted, p).String()
+}
+
+// stress runs the scenario in batches until interrupted, printing
+// rolling statistics every second.
+func stress(s scenario) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	type batch struct {
+		run, probe result
+	}
+	batches := make(chan batch)
+	go func() {
+		for ctx.Err() == nil {
+			batches <- batch{s.run(stressBatch), s.run(1)}
+		}
+		close(batches)
+	}()
+
+	fmt.Printf("stressing %q, interrupt to stop\n", s.name)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var messages, total int
+	var samples []time.Duration
+	var history []float64
+	for {
+		select {
+		case b, ok := <-batches:
+			if !ok {
+				fmt.Printf("%d messages in total\n", total+messages)
+				return
+			}
+			for _, r := range []result{b.run, b.probe} {
+				if r.err != nil && ctx.Err() == nil {
+					fmt.Fprintf(os.Stderr, "%s failed: %v\n", s.name, r.err)
+					os.Exit(1)
+				}
+			}
+			if b.run.err == nil {
+				messages += b.run.messages
+			}
+			// Scenarios that move a fixed number of messages whatever the
+			// iterations asked for have no single message to time.
+			if b.probe.err == nil && b.probe.messages == 1 {
+				samples = append(samples, b.probe.elapsed)
+			}
+
+		case <-ticker.C:
+			var mem runtime.MemStats
+			runtime.ReadMemStats(&mem)
+			slices.Sort(samples)
+			if *tui {
+				history = append(history, float64(messages))
+				history = history[max(0, len(history)-tuiRows*3):]
+				fmt.Print("\x1b[H\x1b[2J")
+				fmt.Printf("stressing %q, interrupt to stop\n%s\n\n", s.name, gcStatus())
+				fmt.Printf("ops/s %s\t%d ops/s\n", sparkline(history), messages)
+				fmt.Printf("message latency p50 %s, p99 %s, from %d probes\n", probed(samples, 50), probed(samples, 99), len(samples))
+			} else {
+				fmt.Printf("%d ops/s\tp99 message latency %s\t%d goroutines\t%d MB heap\n",
+					messages, probed(samples, 99), runtime.NumGoroutine(), mem.HeapAlloc>>20)
+			}
+			total += messages
+			messages, samples = 0, samples[:0]
+		}
+	}
+}
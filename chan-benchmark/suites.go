@@ -0,0 +1,37 @@
+package main
+
+import "flag"
+import "slices"
+
+var suite = flag.String("suite", "full", "run the preset `suite` quick, latency or full")
+
+// suites give the tags whose scenarios each preset runs; a scenario runs
+// if it has any of them, and the full suite runs every scenario.
+var suites = map[string][]string{
+	"quick":   {"micro"},
+	"latency": {"latency"},
+	"full":    nil,
+}
+
+// tags is shorthand for the tags of a scenario: micro and macro tell
+// single operations from whole patterns, latency marks scenarios
+// reporting it, scaling those swept over goroutine counts or
+// capacities, and baseline the references that do not use channels.
+func tags(list ...string) []string {
+	return list
+}
+
+// inSuite returns the scenarios of list that belong to the named suite.
+func inSuite(list []scenario, name string) []scenario {
+	wanted := suites[name]
+	if wanted == nil {
+		return list
+	}
+	var selected []scenario
+	for _, s := range list {
+		if slices.ContainsFunc(s.tags, func(t string) bool { return slices.Contains(wanted, t) }) {
+			selected = append(selected, s)
+		}
+	}
+	return selected
+}
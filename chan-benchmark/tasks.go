@@ -0,0 +1,92 @@
+package main
+
+import "runtime"
+import "sync"
+import "time"
+
+// taskExecutors is the number of goroutines running queued tasks.
+const taskExecutors = 4
+
+// taskKind says which operation a data task asks for.
+type taskKind int
+
+const (
+	taskScale taskKind = iota
+	taskOffset
+	taskFlip
+)
+
+// dataTask describes its work with plain data, for the executor to
+// interpret.
+type dataTask struct {
+	kind  taskKind
+	index int
+}
+
+// runTasks has a producer queue iterations tasks, built by makeTask, for
+// executors that run each one with execute, and reports allocations.
+func runTasks[T any](iterations int, makeTask func(i int, out []int) T, execute func(t T, out []int)) result {
+	out := make([]int, iterations)
+	tasks := make(chan T, taskExecutors)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	then := time.Now()
+
+	go func() {
+		for i := 0; i < iterations; i++ {
+			tasks <- makeTask(i, out)
+		}
+		close(tasks)
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(taskExecutors)
+	for e := 0; e < taskExecutors; e++ {
+		go func() {
+			for t := range tasks {
+				execute(t, out)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	elapsed := time.Since(then)
+	runtime.ReadMemStats(&after)
+	return result{messages: iterations, elapsed: elapsed, metrics: allocations(&before, &after, iterations)}
+}
+
+// closureTasks sends func() closures, each capturing its operands, which
+// executors simply call.
+func closureTasks(iterations int) result {
+	return runTasks(iterations,
+		func(i int, out []int) func() {
+			switch taskKind(i % 3) {
+			case taskScale:
+				return func() { out[i] = i * 3 }
+			case taskOffset:
+				return func() { out[i] = i + 7 }
+			default:
+				return func() { out[i] = ^i }
+			}
+		},
+		func(t func(), out []int) { t() })
+}
+
+// dataTasks sends the same work as plain structs, which executors switch
+// on.
+func dataTasks(iterations int) result {
+	return runTasks(iterations,
+		func(i int, out []int) dataTask { return dataTask{taskKind(i % 3), i} },
+		func(t dataTask, out []int) {
+			switch t.kind {
+			case taskScale:
+				out[t.index] = t.index * 3
+			case taskOffset:
+				out[t.index] = t.index + 7
+			default:
+				out[t.index] = ^t.index
+			}
+		})
+}
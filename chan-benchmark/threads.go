@@ -0,0 +1,17 @@
+package main
+
+import "flag"
+import "runtime"
+
+var lockThreads = flag.Bool("lockthreads", false, "wire the sending and receiving goroutines of streaming scenarios to their own OS threads")
+
+// pinThread wires the calling goroutine to its OS thread when
+// -lockthreads is set, so that every handoff crosses threads. The
+// returned function releases the thread again.
+func pinThread() func() {
+	if !*lockThreads {
+		return func() {}
+	}
+	runtime.LockOSThread()
+	return runtime.UnlockOSThread
+}
@@ -0,0 +1,68 @@
+package main
+
+import "bufio"
+import "os"
+import "path/filepath"
+import "strconv"
+import "strings"
+import "time"
+
+// readThrottling returns how often and for how long the CFS quota of
+// this process's cgroup has throttled it, from cgroup v1 or v2.
+func readThrottling() throttling {
+	for _, path := range cpuStatPaths() {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		var t throttling
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			name, value, _ := strings.Cut(scanner.Text(), " ")
+			v, _ := strconv.ParseUint(value, 10, 64)
+			switch name {
+			case "nr_throttled":
+				t.periods = v
+			case "throttled_usec":
+				t.time = time.Duration(v) * time.Microsecond
+			case "throttled_time":
+				t.time = time.Duration(v)
+			}
+		}
+		return t
+	}
+	return throttling{}
+}
+
+// cpuStatPaths returns where the cpu.stat of this process's cgroup may
+// be, most likely first: under the v1 hierarchy of the cpu controller if
+// there is one, else under the v2 hierarchy, as listed in
+// /proc/self/cgroup. The hierarchy roots come last, for a container
+// whose cgroup is mounted there without a cgroup namespace.
+func cpuStatPaths() []string {
+	var v1, v2 []string
+	if b, err := os.ReadFile("/proc/self/cgroup"); err == nil {
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			fields := strings.SplitN(line, ":", 3)
+			if len(fields) != 3 {
+				continue
+			}
+			controllers, path := fields[1], fields[2]
+			if controllers == "" {
+				v2 = append(v2,
+					filepath.Join("/sys/fs/cgroup", path, "cpu.stat"),
+					filepath.Join("/sys/fs/cgroup/unified", path, "cpu.stat"))
+				continue
+			}
+			for _, c := range strings.Split(controllers, ",") {
+				if c == "cpu" {
+					v1 = append(v1,
+						filepath.Join("/sys/fs/cgroup", controllers, path, "cpu.stat"),
+						filepath.Join("/sys/fs/cgroup/cpu", path, "cpu.stat"))
+				}
+			}
+		}
+	}
+	return append(append(v1, v2...), "/sys/fs/cgroup/cpu/cpu.stat", "/sys/fs/cgroup/cpu,cpuacct/cpu.stat", "/sys/fs/cgroup/cpu.stat")
+}
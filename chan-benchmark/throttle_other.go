@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// readThrottling is not available on this platform.
+func readThrottling() throttling {
+	return throttling{}
+}
@@ -0,0 +1,88 @@
+package main
+
+import "context"
+import "fmt"
+import "runtime"
+import "time"
+
+// receiveTimeout is the deadline guarding each receive; it never fires.
+const receiveTimeout = time.Second
+
+// guardedReceives streams iterations messages to a consumer that runs
+// receive for each one, and reports the allocations made, along with how
+// many more goroutines were running halfway through and how much heap
+// was still held when it finished.
+func guardedReceives(iterations int, receive func(ch <-chan int) bool) result {
+	ch := make(chan int)
+	go func() {
+		for i := 0; i < iterations; i++ {
+			ch <- i
+		}
+		close(ch)
+	}()
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	goroutines := runtime.NumGoroutine()
+	then := time.Now()
+
+	messages, growth := 0, 0
+	for receive(ch) {
+		messages++
+		if messages == iterations/2 {
+			growth = runtime.NumGoroutine() - goroutines
+		}
+	}
+
+	elapsed := time.Since(then)
+	runtime.ReadMemStats(&after)
+	metrics := append(allocations(&before, &after, max(1, messages)),
+		metric{"goroutine growth", float64(growth), ""},
+		metric{"heap growth", float64(int64(after.HeapInuse) - int64(before.HeapInuse)), "B"},
+	)
+	return result{messages: messages, elapsed: elapsed, metrics: metrics}
+}
+
+// contextPerReceive guards every receive with a fresh context.WithTimeout.
+func contextPerReceive(iterations int) result {
+	return guardedReceives(iterations, func(ch <-chan int) bool {
+		ctx, cancel := context.WithTimeout(context.Background(), receiveTimeout)
+		defer cancel()
+		select {
+		case _, ok := <-ch:
+			return ok
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// timeAfterReceive guards every receive with time.After, whose timer
+// cannot be stopped and stays pending until it fires.
+func timeAfterReceive(iterations int) result {
+	r := guardedReceives(iterations, func(ch <-chan int) bool {
+		select {
+		case _, ok := <-ch:
+			return ok
+		case <-time.After(receiveTimeout):
+			return false
+		}
+	})
+	r.notes = append(r.notes, fmt.Sprintf("every receive leaves a timer pending for %v", receiveTimeout))
+	return r
+}
+
+// reusedTimerReceive guards every receive with one timer, reset each time.
+func reusedTimerReceive(iterations int) result {
+	timer := time.NewTimer(receiveTimeout)
+	defer timer.Stop()
+	return guardedReceives(iterations, func(ch <-chan int) bool {
+		timer.Reset(receiveTimeout)
+		select {
+		case _, ok := <-ch:
+			return ok
+		case <-timer.C:
+			return false
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import "flag"
+import "fmt"
+import "os"
+import "runtime"
+import "time"
+
+var topologyPairs = flag.Bool("topology", false, "report the CPU topology, and run ping-pong pinned to CPUs on the same core, same socket and different sockets (Linux only)")
+
+type cpuInfo struct {
+	id, socket, core int
+}
+
+// cpuPair is two CPUs at some distance from each other.
+type cpuPair struct {
+	distance string
+	a, b     cpuInfo
+}
+
+// cpuPairs finds a pair of CPUs for each distance that the machine has:
+// hyperthreads of one core, cores of one socket, and different sockets.
+func cpuPairs(cpus []cpuInfo) []cpuPair {
+	var pairs []cpuPair
+	for _, d := range []struct {
+		name  string
+		match func(a, b cpuInfo) bool
+	}{
+		{"same core", func(a, b cpuInfo) bool { return a.socket == b.socket && a.core == b.core }},
+		{"same socket", func(a, b cpuInfo) bool { return a.socket == b.socket && a.core != b.core }},
+		{"cross socket", func(a, b cpuInfo) bool { return a.socket != b.socket }},
+	} {
+	search:
+		for i, a := range cpus {
+			for _, b := range cpus[i+1:] {
+				if d.match(a, b) {
+					pairs = append(pairs, cpuPair{d.name, a, b})
+					break search
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// topologyScenarios describes the topology on stderr, and returns a
+// pinned ping-pong scenario for each pair of CPUs found.
+func topologyScenarios() []scenario {
+	cpus := readTopology()
+	sockets := make(map[int]bool)
+	cores := make(map[[2]int]bool)
+	for _, c := range cpus {
+		sockets[c.socket] = true
+		cores[[2]int{c.socket, c.core}] = true
+	}
+	fmt.Fprintf(os.Stderr, "topology: %d sockets, %d cores, %d CPUs\n", len(sockets), len(cores), len(cpus))
+
+	var list []scenario
+	for _, p := range cpuPairs(cpus) {
+		list = append(list, scenario{"ping-pong pinned, " + p.distance, pinnedPingPong(p), tags("micro", "latency")})
+	}
+	return list
+}
+
+// pinnedPingPong is the unbuffered ping-pong scenario with each side
+// pinned to one CPU of the pair.
+func pinnedPingPong(p cpuPair) func(iterations int) result {
+	return func(iterations int) result {
+		ping := make(chan int)
+		pong := make(chan int)
+		ready := make(chan error)
+
+		go func() {
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+			restore, err := pinToCPU(p.b.id)
+			ready <- err
+			if err != nil {
+				return
+			}
+			defer restore()
+			for v := range ping {
+				pong <- v
+			}
+		}()
+
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		restore, err := pinToCPU(p.a.id)
+		otherErr := <-ready
+		if err == nil {
+			defer restore()
+		}
+		if err == nil && otherErr != nil {
+			err = otherErr
+		}
+		if err != nil {
+			close(ping)
+			return result{err: err}
+		}
+
+		then := time.Now()
+
+		for i := 0; i < iterations; i++ {
+			ping <- i
+			<-pong
+		}
+		elapsed := time.Since(then)
+		close(ping)
+
+		return result{
+			messages: iterations,
+			elapsed:  elapsed,
+			metrics:  []metric{{"one-way latency", float64(elapsed) / float64(2*iterations), "ns"}},
+			notes:    []string{fmt.Sprintf("CPUs %d and %d", p.a.id, p.b.id)},
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "strconv"
+import "strings"
+import "syscall"
+import "unsafe"
+
+// readTopology lists the online CPUs with their socket and core, from
+// sysfs.
+func readTopology() []cpuInfo {
+	dirs, _ := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/topology")
+	var cpus []cpuInfo
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(dir)), "cpu"))
+		if err != nil {
+			continue
+		}
+		socket, err1 := readSysInt(filepath.Join(dir, "physical_package_id"))
+		core, err2 := readSysInt(filepath.Join(dir, "core_id"))
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		cpus = append(cpus, cpuInfo{id, socket, core})
+	}
+	return cpus
+}
+
+func readSysInt(path string) (int, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+// maskCPUs is the number of CPUs a cpuMask holds, CPU_SETSIZE in glibc.
+const maskCPUs = 1024
+
+// cpuMask is a sched_setaffinity mask for up to maskCPUs CPUs.
+type cpuMask [maskCPUs / 64]uint64
+
+func schedAffinity(trap uintptr, mask *cpuMask) error {
+	_, _, errno := syscall.RawSyscall(trap, 0, unsafe.Sizeof(*mask), uintptr(unsafe.Pointer(mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// pinToCPU restricts the calling thread, which must be locked to its
+// goroutine, to one CPU. The returned function restores its affinity.
+func pinToCPU(cpu int) (restore func(), err error) {
+	if cpu < 0 || cpu >= maskCPUs {
+		return nil, fmt.Errorf("cannot pin to cpu %d: the affinity mask holds cpus 0 to %d", cpu, maskCPUs-1)
+	}
+	var saved cpuMask
+	if err := schedAffinity(syscall.SYS_SCHED_GETAFFINITY, &saved); err != nil {
+		return nil, os.NewSyscallError("sched_getaffinity", err)
+	}
+	var mask cpuMask
+	mask[cpu/64] |= 1 << (cpu % 64)
+	if err := schedAffinity(syscall.SYS_SCHED_SETAFFINITY, &mask); err != nil {
+		return nil, os.NewSyscallError("sched_setaffinity", err)
+	}
+	return func() { schedAffinity(syscall.SYS_SCHED_SETAFFINITY, &saved) }, nil
+}
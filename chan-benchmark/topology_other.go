@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// readTopology is not available on this platform.
+func readTopology() []cpuInfo {
+	return nil
+}
+
+func pinToCPU(cpu int) (restore func(), err error) {
+	return nil, errors.New("CPU affinity is only supported on Linux")
+}
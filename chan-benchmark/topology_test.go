@@ -0,0 +1,12 @@
+package main
+
+import "testing"
+
+func TestPinToCPUOutOfRange(t *testing.T) {
+	for _, cpu := range []int{-1, 1024, 4096} {
+		if restore, err := pinToCPU(cpu); err == nil {
+			restore()
+			t.Errorf("pinToCPU(%d) succeeded", cpu)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import "flag"
+import "fmt"
+import "io"
+import "math"
+import "runtime"
+import "strings"
+import "sync"
+import "time"
+
+var tui = flag.Bool("tui", false, "show a live dashboard on standard output while scenarios or -stress run, and write the results there once they are done")
+
+// tuiRefresh is how often the dashboard is redrawn.
+const tuiRefresh = 250 * time.Millisecond
+
+// tuiRows is the number of finished scenarios the dashboard lists, and
+// the length of its sparklines.
+const tuiRows = 20
+
+// sparkline draws values as a row of bars scaled to the largest one.
+// Values that are negative or not finite are drawn as the lowest bar.
+func sparkline(values []float64) string {
+	const bars = "▁▂▃▄▅▆▇█"
+	levels := []rune(bars)
+	top := 0.0
+	for _, v := range values {
+		if !math.IsInf(v, 0) {
+			top = math.Max(top, v)
+		}
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if top > 0 && v > 0 && v <= top {
+			i = min(len(levels)-1, int(v/top*float64(len(levels)-1)))
+		}
+		b.WriteRune(levels[i])
+	}
+	return b.String()
+}
+
+// gcStatus describes the collections so far, and the latest pause.
+func gcStatus() string {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	last := time.Duration(mem.PauseNs[(mem.NumGC+255)%256])
+	return fmt.Sprintf("%d GC cycles, last pause %v, %d MB heap, %d goroutines",
+		mem.NumGC, last, mem.HeapAlloc>>20, runtime.NumGoroutine())
+}
+
+// dashboard is an output that redraws the state of the run in place:
+// the scenario in progress, the latest results with their percentiles,
+// a sparkline of their throughput, and garbage collection activity.
+type dashboard struct {
+	w    io.Writer
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+
+	current    string
+	started    time.Time
+	lines      []string
+	throughput []float64
+	finished   int
+	total      int
+}
+
+func newDashboard(w io.Writer, scenarios int) *dashboard {
+	d := &dashboard{w: w, stop: make(chan struct{}), done: make(chan struct{}), total: scenarios}
+	go func() {
+		ticker := time.NewTicker(tuiRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.draw()
+			case <-d.stop:
+				d.draw()
+				close(d.done)
+				return
+			}
+		}
+	}()
+	return d
+}
+
+// running notes that the named scenario has started.
+func (d *dashboard) running(name string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.current, d.started = name, time.Now()
+	d.mu.Unlock()
+}
+
+func (d *dashboard) draw() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fmt.Fprint(d.w, "\x1b[H\x1b[2J")
+	fmt.Fprintf(d.w, "%d of %d scenarios done\t%s\n", d.finished, d.total, gcStatus())
+	if d.current != "" {
+		fmt.Fprintf(d.w, "running %s for %v\n", d.current, time.Since(d.started).Round(time.Millisecond))
+	}
+	fmt.Fprintf(d.w, "\nmsg/s %s\n\n", sparkline(d.throughput[max(0, len(d.throughput)-tuiRows):]))
+	for _, l := range d.lines {
+		fmt.Fprintln(d.w, l)
+	}
+}
+
+func (d *dashboard) result(name string, r result) {
+	throughput := "-"
+	if r.elapsed > 0 {
+		throughput = formatValue(float64(r.messages) / r.elapsed.Seconds())
+	}
+	line := fmt.Sprintf("%-34s %12s msg/s", name, throughput)
+	for _, m := range r.metrics {
+		if (strings.HasPrefix(m.name, "p50 ") || strings.HasPrefix(m.name, "p99 ")) && !strings.HasSuffix(m.name, "sched latency") {
+			line += fmt.Sprintf("  %s %s %s", m.name, formatValue(m.value), m.unit)
+		}
+	}
+	d.mu.Lock()
+	d.current = ""
+	d.finished++
+	if r.elapsed > 0 {
+		d.throughput = append(d.throughput, float64(r.messages)/r.elapsed.Seconds())
+	}
+	d.lines = append(d.lines, line)
+	if len(d.lines) > tuiRows {
+		d.lines = d.lines[1:]
+	}
+	d.mu.Unlock()
+}
+
+func (d *dashboard) footprints(list []footprint) {}
+
+func (d *dashboard) loadCurve(curve []loadPoint) {}
+
+func (d *dashboard) recommendation(rec recommendation) {}
+
+func (d *dashboard) skipped(names []string) {}
+
+func (d *dashboard) failed(name string, err error) {
+	d.mu.Lock()
+	d.current = ""
+	d.finished++
+	d.lines = append(d.lines, fmt.Sprintf("%-34s failed: %v", name, err))
+	if len(d.lines) > tuiRows {
+		d.lines = d.lines[1:]
+	}
+	d.mu.Unlock()
+}
+
+func (d *dashboard) finish(partial bool) {
+	close(d.stop)
+	<-d.done
+}
@@ -0,0 +1,143 @@
+package main
+
+import "flag"
+import "fmt"
+import "os"
+
+var verify = flag.Bool("verify", false, "check that the ordered scenarios, such as streams, ping-pongs, fan-ins and pipelines, deliver every message exactly once and in order from each producer, as noted on their results; a failure makes the exit status 1")
+
+// verifyFailures counts the scenarios that failed -verify.
+var verifyFailures int
+
+// sequenceCheck follows the sequence numbers received from each
+// producer, which must each send 0, 1, 2 and so on. It is nil unless
+// -verify is set, so that unverified runs pay only for a nil test.
+type sequenceCheck struct {
+	next        []int
+	perProducer int
+	// dropped, when set, tells the messages meant to be dropped on the
+	// way, which count as received, and gaps accepts any message missing
+	// as dropped; drops counts them, and reported is the number of drops
+	// reported out of band, or -1.
+	dropped  func(seq int) bool
+	gaps     bool
+	drops    int
+	reported int
+	// problem describes the first violation seen, and errors counts all.
+	problem string
+	errors  int
+}
+
+func newSequenceCheck(producers, perProducer int) *sequenceCheck {
+	if !*verify {
+		return nil
+	}
+	return &sequenceCheck{next: make([]int, producers), perProducer: perProducer, reported: -1}
+}
+
+// allowDrops accepts the messages for which dropped is true as missing.
+func (c *sequenceCheck) allowDrops(dropped func(seq int) bool) {
+	if c != nil {
+		c.dropped = dropped
+	}
+}
+
+// allowGaps accepts any message as dropped, provided that those received
+// from each producer still arrive in order.
+func (c *sequenceCheck) allowGaps() {
+	if c != nil {
+		c.gaps = true
+	}
+}
+
+// dropsReported records how many messages were reported as dropped, out
+// of band, which must match those missing.
+func (c *sequenceCheck) dropsReported(drops int) {
+	if c != nil {
+		c.reported = drops
+	}
+}
+
+// remote records the outcome of a check made in another process, which
+// received messages in all, the first of its errors being seq in place
+// of want.
+func (c *sequenceCheck) remote(producer, received, errors, seq, want int) {
+	if c != nil {
+		c.next[producer] = received
+		if errors > 0 {
+			c.problem = describe(producer, seq, want)
+			c.errors += errors
+		}
+	}
+}
+
+// receive records that message seq from producer arrived.
+func (c *sequenceCheck) receive(producer, seq int) {
+	if c != nil {
+		c.observe(producer, seq)
+	}
+}
+
+func (c *sequenceCheck) observe(producer, seq int) {
+	want := c.skip(producer, seq)
+	if seq != want {
+		if c.errors == 0 {
+			c.problem = describe(producer, seq, want)
+		}
+		c.errors++
+	}
+	c.next[producer] = max(want, seq+1)
+}
+
+// skip moves past the messages of producer before seq that were meant
+// to be dropped, or past all of them when gaps are allowed, and returns
+// the next one expected.
+func (c *sequenceCheck) skip(producer, seq int) int {
+	for c.dropped != nil && c.next[producer] < seq && c.dropped(c.next[producer]) {
+		c.next[producer]++
+		c.drops++
+	}
+	if c.gaps && c.next[producer] < seq {
+		c.drops += seq - c.next[producer]
+		c.next[producer] = seq
+	}
+	return c.next[producer]
+}
+
+func describe(producer, seq, want int) string {
+	if seq < want {
+		return fmt.Sprintf("producer %d: message %d received again after %d", producer, seq, want-1)
+	}
+	return fmt.Sprintf("producer %d: message %d received while %d was expected", producer, seq, want)
+}
+
+// verified returns r, noting whether every message arrived once and in
+// order.
+func (c *sequenceCheck) verified(r result) result {
+	if c == nil {
+		return r
+	}
+	for p := range c.next {
+		if n := c.skip(p, c.perProducer); n != c.perProducer && c.errors == 0 {
+			c.problem = fmt.Sprintf("producer %d: %d of %d messages received", p, n, c.perProducer)
+			c.errors++
+		}
+	}
+	if c.reported >= 0 && c.reported != c.drops && c.errors == 0 {
+		c.problem = fmt.Sprintf("%d messages reported dropped, of %d expected", c.reported, c.drops)
+		c.errors++
+	}
+	if c.errors == 0 && c.drops > 0 {
+		r.notes = append(r.notes, fmt.Sprintf("verified: all %d messages received once, in order from each producer, and the other %d reported dropped", c.perProducer*len(c.next)-c.drops, c.drops))
+		return r
+	}
+	if c.errors == 0 {
+		r.notes = append(r.notes, fmt.Sprintf("verified: all %d messages received once, in order from each producer", c.perProducer*len(c.next)))
+		return r
+	}
+	verifyFailures++
+	r.metrics = append(r.metrics, metric{"ordering errors", float64(c.errors), ""})
+	r.notes = append(r.notes, "verification failed: "+c.problem)
+	fmt.Fprintln(os.Stderr, "verification failed:", c.problem)
+	return r
+}
@@ -0,0 +1,88 @@
+package main
+
+import "strings"
+import "testing"
+
+// checked runs the received sequence numbers, given per producer as
+// p*n+i, through a sequence check, and returns the note on the result
+// and whether it failed.
+func checked(t *testing.T, producers, n int, setup func(*sequenceCheck), received ...int) (string, bool) {
+	t.Helper()
+	saved, failures := *verify, verifyFailures
+	t.Cleanup(func() { *verify, verifyFailures = saved, failures })
+	*verify = true
+
+	check := newSequenceCheck(producers, n)
+	if setup != nil {
+		setup(check)
+	}
+	for _, v := range received {
+		check.receive(v/n, v%n)
+	}
+	before := verifyFailures
+	r := check.verified(result{})
+	if len(r.notes) != 1 {
+		t.Fatalf("notes %q, want one", r.notes)
+	}
+	return r.notes[0], verifyFailures != before
+}
+
+func TestSequenceCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		producers int
+		n         int
+		setup     func(*sequenceCheck)
+		received  []int
+		fails     bool
+		note      string
+	}{
+		{"in order", 1, 4, nil, []int{0, 1, 2, 3}, false, "all 4 messages"},
+		{"interleaved producers", 2, 4, nil, []int{0, 4, 1, 5, 6, 2, 3, 7}, false, "all 8 messages"},
+		{"duplicate", 1, 4, nil, []int{0, 1, 1, 2, 3}, true, "message 1 received again after 1"},
+		{"reordered", 1, 4, nil, []int{0, 2, 1, 3}, true, "message 2 received while 1 was expected"},
+		{"missing", 1, 4, nil, []int{0, 1, 2}, true, "3 of 4 messages received"},
+		{"reordered within a producer", 2, 4, nil, []int{0, 1, 4, 6, 5, 7, 2, 3}, true, "producer 1: message 2 received while 1 was expected"},
+		{"allowed drops", 1, 4, func(c *sequenceCheck) {
+			c.allowDrops(func(seq int) bool { return seq%2 == 1 })
+			c.dropsReported(2)
+		}, []int{0, 2}, false, "all 2 messages received once, in order from each producer, and the other 2 reported dropped"},
+		{"drops misreported", 1, 4, func(c *sequenceCheck) {
+			c.allowDrops(func(seq int) bool { return seq%2 == 1 })
+			c.dropsReported(1)
+		}, []int{0, 2}, true, "1 messages reported dropped, of 2 expected"},
+		{"unexpected drop", 1, 4, func(c *sequenceCheck) {
+			c.allowDrops(func(seq int) bool { return seq == 1 })
+		}, []int{0, 3}, true, "message 3 received while 2 was expected"},
+		{"gaps", 2, 4, func(c *sequenceCheck) {
+			c.allowGaps()
+			c.dropsReported(4)
+		}, []int{4, 1, 7, 3}, false, "all 4 messages received once, in order from each producer, and the other 4 reported dropped"},
+		{"gaps out of order", 1, 4, (*sequenceCheck).allowGaps, []int{0, 2, 1}, true, "message 1 received again after 2"},
+		{"gaps duplicate", 1, 4, (*sequenceCheck).allowGaps, []int{1, 1}, true, "message 1 received again after 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			note, failed := checked(t, tt.producers, tt.n, tt.setup, tt.received...)
+			if failed != tt.fails || !strings.Contains(note, tt.note) {
+				t.Errorf("note %q, failed %v; want %q, failed %v", note, failed, tt.note, tt.fails)
+			}
+		})
+	}
+}
+
+func TestSequenceCheckUnverified(t *testing.T) {
+	saved := *verify
+	t.Cleanup(func() { *verify = saved })
+	*verify = false
+
+	check := newSequenceCheck(1, 2)
+	if check != nil {
+		t.Fatal("a sequence check without -verify")
+	}
+	check.allowGaps()
+	check.receive(0, 5)
+	if r := check.verified(result{messages: 2}); len(r.notes) != 0 || r.messages != 2 {
+		t.Errorf("unverified result %+v, want it unchanged", r)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import "flag"
+import "sync"
+import "time"
+
+var jobCost = flag.String("jobcost", "exponential", "distribution of worker pool job costs: `dist` constant, uniform or exponential")
+
+var jobWork = flag.Int("jobwork", 1000, "mean cost of a worker pool job, in iterations of busy work")
+
+var poolWorkers = []int{1, 2, 4, 8, 16}
+
+// poolJobs is the fraction of iterations run as worker pool jobs, which
+// cost much more than a message.
+const poolJobs = 10
+
+func workerPoolScenarios() []scenario {
+	var list []scenario
+	for _, w := range poolWorkers {
+		list = append(list, scenario{"worker pool, " + plural(w, "worker"), workerPool(w), tags("macro", "scaling")})
+	}
+	return list
+}
+
+// jobCosts draws n job costs from the -jobcost distribution.
+func jobCosts(n int) []int {
+	rng := newRand()
+	mean := float64(*jobWork)
+	costs := make([]int, n)
+	for i := range costs {
+		switch *jobCost {
+		case "constant":
+			costs[i] = int(mean)
+		case "uniform":
+			costs[i] = int(rng.Float64() * 2 * mean)
+		default:
+			costs[i] = int(rng.ExpFloat64() * mean)
+		}
+	}
+	return costs
+}
+
+type poolJob struct {
+	cost     int
+	enqueued time.Time
+}
+
+// workerPool feeds jobs to a bounded pool of workers, whose results are
+// gathered by a single aggregator. Job latency runs from enqueueing a
+// job to the aggregator receiving its result.
+func workerPool(workers int) func(iterations int) result {
+	return func(iterations int) result {
+		costs := jobCosts(max(1, iterations/poolJobs))
+		jobs := make(chan poolJob, workers)
+		results := make(chan time.Time, workers)
+
+		then := time.Now()
+
+		go func() {
+			for _, c := range costs {
+				jobs <- poolJob{c, time.Now()}
+			}
+			close(jobs)
+		}()
+
+		participants := make([]participant, workers)
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for w := 0; w < workers; w++ {
+			go func(w int) {
+				start := time.Now()
+				var blocked time.Duration
+				jobsDone, busy := 0, 0
+				for {
+					t := time.Now()
+					j, ok := <-jobs
+					if !ok {
+						break
+					}
+					blocked += time.Since(t)
+					busy += work(j.cost)
+					t = time.Now()
+					results <- j.enqueued
+					blocked += time.Since(t)
+					jobsDone++
+				}
+				participants[w] = participant{"worker", w, jobsDone, blocked, time.Since(start)}
+				sink(busy)
+				wg.Done()
+			}(w)
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		latencies := make([]time.Duration, 0, len(costs))
+		for enqueued := range results {
+			latencies = append(latencies, time.Since(enqueued))
+		}
+
+		return result{
+			messages:     len(latencies),
+			elapsed:      time.Since(then),
+			metrics:      distribution("job latency", latencies),
+			sweep:        "worker pool",
+			parallelism:  workers,
+			participants: participants,
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import "runtime"
+import "time"
+
+// goschedAlone calls runtime.Gosched with no other goroutine wanting
+// to run, which is the cost of entering the scheduler and coming back.
+func goschedAlone(iterations int) result {
+	then := time.Now()
+	for i := 0; i < iterations; i++ {
+		runtime.Gosched()
+	}
+	return result{messages: iterations, elapsed: time.Since(then)}
+}
+
+// goschedPair has two goroutines yield to each other on a single P, so
+// that each Gosched switches to the other goroutine.
+func goschedPair(iterations int) result {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	done := make(chan struct{})
+	then := time.Now()
+	go func() {
+		for i := 0; i < iterations; i++ {
+			runtime.Gosched()
+		}
+		close(done)
+	}()
+	for i := 0; i < iterations; i++ {
+		runtime.Gosched()
+	}
+	<-done
+	return result{messages: iterations, elapsed: time.Since(then)}
+}
+
+// parkRoundTrip wakes a parked goroutine and parks until it wakes this
+// one in return, using semaphores rather than channels; each iteration
+// is two parks and two wakeups.
+func parkRoundTrip(iterations int) result {
+	ping, pong := newSemaphore(0), newSemaphore(0)
+	go func() {
+		for i := 0; i < iterations; i++ {
+			ping.wait()
+			pong.signal()
+		}
+	}()
+
+	then := time.Now()
+	for i := 0; i < iterations; i++ {
+		ping.signal()
+		pong.wait()
+	}
+	return result{messages: iterations, elapsed: time.Since(then)}
+}